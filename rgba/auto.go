@@ -0,0 +1,122 @@
+package rgba
+
+import (
+	"fmt"
+)
+
+// AutoIndexer is an Indexer which looks at each palette it is asked to index and
+// picks the fastest of the other indexers in this package that meets the requested
+// accuracy. Use Choose to find out what it would pick, and why.
+//
+// The zero value requests exact results and assumes a large number of lookups.
+//
+// The choices are based on the benchmarks in this package (see BenchmarkIndexSearch
+// and BenchmarkIndexBuild) rather than anything more principled, so they may not be
+// right for your hardware.
+//
+type AutoIndexer struct {
+	// Lookups is the expected number of lookups that will be made against each
+	// index. Building some indexes is much more expensive than others, which is only
+	// worth it if the index is used enough. 0 means "lots".
+	Lookups int
+
+	// Tolerance is the maximum amount that each channel of a query colour may be
+	// rounded by before the nearest neighbour is searched for. 0 requires exact
+	// results. The precache indexers discard the 3 least significant bits of each
+	// channel, so they need a Tolerance of at least 7.
+	Tolerance uint8
+}
+
+var _ Indexer = AutoIndexer{}
+
+const (
+	// autoPrecacheLookups is roughly the number of lookups after which the cost of
+	// building an RGBPrecacheIndex (which performs one tree lookup for each of its
+	// 32768 cells) is paid back.
+	autoPrecacheLookups = 65536
+
+	// autoOrchardMinLookups is the fewest lookups for which the Orchard indexer is
+	// preferred over the trees, whatever the palette size. See autoOrchardLookups.
+	autoOrchardMinLookups = 16384
+
+	autoPrecacheTolerance = 7
+)
+
+// autoOrchardLookups returns roughly the number of lookups after which the Orchard
+// indexer's faster searches pay back its O(n²) build cost, compared to the trees.
+//
+// Orchard searches faster than the trees for every palette size in
+// BenchmarkIndexSearch, by about 15ns per lookup at 16 entries up to about 150ns at
+// 256. Building its distance matrix costs about 150-300ns per pair of entries,
+// against a few µs for a tree, which comes to between about 2,000 and 90,000 lookups
+// to break even. 2n², with a floor, errs on the side of the trees.
+//
+func autoOrchardLookups(n int) int {
+	if l := 2 * n * n; l > autoOrchardMinLookups {
+		return l
+	}
+	return autoOrchardMinLookups
+}
+
+func (ai AutoIndexer) IndexRGBAPalette(pal Palette) Index {
+	indexer, _ := ai.Choose(pal)
+	return indexer.IndexRGBAPalette(pal)
+}
+
+// Choose returns the Indexer that would be used for pal, along with a
+// human-readable explanation of why.
+func (ai AutoIndexer) Choose(pal Palette) (indexer Indexer, reason string) {
+	opaque := true
+	for _, c := range pal {
+		if c.A != 0xff {
+			opaque = false
+			break
+		}
+	}
+
+	manyLookups := ai.Lookups <= 0 || ai.Lookups >= autoPrecacheLookups
+	lookups := "many lookups"
+	if ai.Lookups > 0 {
+		lookups = fmt.Sprintf("%d lookups", ai.Lookups)
+	}
+
+	orchard := ai.Lookups <= 0 || ai.Lookups >= autoOrchardLookups(len(pal))
+
+	if !opaque {
+		if orchard {
+			return NewOrchardIndexer(MetricRGBA), fmt.Sprintf(
+				"orchard (rgba): palette has non-opaque entries so alpha must be searched, "+
+					"and %s will pay back building the distance matrix for %d entries",
+				lookups, len(pal))
+		}
+		return NewRGBATreeIndexer(), fmt.Sprintf(
+			"rgba tree: palette has non-opaque entries so alpha must be searched, "+
+				"and %s won't pay back building the orchard distance matrix for %d entries",
+			lookups, len(pal))
+	}
+
+	// If every entry is opaque, the alpha of the query adds the same amount to the
+	// distance to every entry, so the RGB indexers give the same answers as the RGBA
+	// ones for less work.
+
+	if ai.Tolerance >= autoPrecacheTolerance && manyLookups {
+		return NewRGBPrecacheIndexer(nil), fmt.Sprintf(
+			"rgb precache: palette is opaque, tolerance %d allows 5-bit lookups, "+
+				"and %s will pay back the build cost", ai.Tolerance, lookups)
+	}
+
+	why := fmt.Sprintf("tolerance %d requires exact results", ai.Tolerance)
+	if ai.Tolerance >= autoPrecacheTolerance {
+		why = fmt.Sprintf("%s won't pay back the precache build cost", lookups)
+	}
+
+	if orchard {
+		return NewOrchardIndexer(MetricRGB), fmt.Sprintf(
+			"orchard (rgb): palette is opaque, %s, and %s will pay back building the "+
+				"distance matrix for %d entries", why, lookups, len(pal))
+	}
+
+	return NewRGBTreeIndexer(), fmt.Sprintf(
+		"rgb tree: palette is opaque, %s, and %s won't pay back building the orchard "+
+			"distance matrix for %d entries", why, lookups, len(pal))
+}
@@ -0,0 +1,68 @@
+package rgba
+
+import (
+	"fmt"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func TestAutoIndexerChoose(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	opaque16 := ConvertPalette(testimg.RandPalette(rng, 16))
+	opaque64 := ConvertPalette(testimg.RandPalette(rng, 64))
+	alpha16 := append(Palette{{}}, opaque16[1:]...)
+	alpha64 := append(Palette{{}}, opaque64[1:]...)
+	alpha256 := append(Palette{{}}, ConvertPalette(testimg.RandPalette(rng, 256))[1:]...)
+
+	for idx, tc := range []struct {
+		auto     AutoIndexer
+		pal      Palette
+		expected Indexer
+	}{
+		{AutoIndexer{}, opaque16, &orchardIndexer{}},
+		{AutoIndexer{}, opaque64, &orchardIndexer{}},
+		{AutoIndexer{}, alpha16, &orchardIndexer{}},
+		{AutoIndexer{}, alpha64, &orchardIndexer{}},
+		{AutoIndexer{Lookups: 1000}, opaque16, &rgbTreeIndexer{}},
+		{AutoIndexer{Lookups: 1000}, alpha16, &rgbaTreeIndexer{}},
+		{AutoIndexer{Lookups: 20000}, opaque64, &orchardIndexer{}},
+		{AutoIndexer{Lookups: 20000}, alpha256, &rgbaTreeIndexer{}},
+		{AutoIndexer{Lookups: 200000}, alpha256, &orchardIndexer{}},
+		{AutoIndexer{Tolerance: 7}, opaque64, &rgbPrecacheIndexer{}},
+		{AutoIndexer{Tolerance: 7, Lookups: 1000}, opaque64, &rgbTreeIndexer{}},
+		{AutoIndexer{Tolerance: 7, Lookups: 1000000}, opaque64, &rgbPrecacheIndexer{}},
+		{AutoIndexer{Tolerance: 7}, alpha64, &orchardIndexer{}},
+	} {
+		t.Run(fmt.Sprint(idx), func(t *testing.T) {
+			indexer, reason := tc.auto.Choose(tc.pal)
+			if fmt.Sprintf("%T", indexer) != fmt.Sprintf("%T", tc.expected) {
+				t.Fatalf("expected %T, found %T: %s", tc.expected, indexer, reason)
+			}
+			if reason == "" {
+				t.Fatal("missing reason")
+			}
+		})
+	}
+}
+
+func TestAutoIndexerExact(t *testing.T) {
+	const iter = 1000
+
+	rng := rand.New(rand.NewSource(0))
+	for _, pc := range paletteCases(rng) {
+		rpal := ConvertPalette(pc.pal)
+		idx := AutoIndexer{}.IndexRGBAPalette(rpal)
+
+		for i := 0; i < iter; i++ {
+			col := testimg.RandRGBA(rng)
+			cnv1 := pc.pal.Convert(col).(color.RGBA)
+			cnv2 := idx.NearestRGBAColor(col)
+			if dist1, dist2 := MetricRGBA(cnv1, col), MetricRGBA(cnv2, col); dist1 != dist2 {
+				t.Fatal(pc.name, i, "col:", col, "expected:", cnv1, "found:", cnv2)
+			}
+		}
+	}
+}
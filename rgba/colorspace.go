@@ -0,0 +1,326 @@
+package rgba
+
+import (
+	"image/color"
+	"math"
+)
+
+// The colour types in this file all hold non-premultiplied colours in float64,
+// with an Alpha from 0 to 1. They convert to and from RGB through gamma-encoded
+// sRGB (and, for all but HSL and HSV, linear sRGB), using the D65 white point.
+//
+// Each has a color.Model, and implements color.Color. Converting from a
+// color.RGBA, color.NRGBA or any other type in this file keeps every bit of
+// precision, so converting a color.RGBA to any of these types and back with
+// color.RGBAModel gives the same color.RGBA, and converting a color.NRGBA and back
+// with the type's NRGBA method gives the same color.NRGBA. color.NRGBAModel
+// itself can't be used for that, as it only sees the premultiplied result of
+// RGBA.
+//
+// Colours outside the sRGB gamut are clamped when they are converted to RGB.
+
+var (
+	HSLModel   color.Model = color.ModelFunc(hslModel)
+	HSVModel   color.Model = color.ModelFunc(hsvModel)
+	XYZModel   color.Model = color.ModelFunc(xyzModel)
+	LabModel   color.Model = color.ModelFunc(labModel)
+	LChModel   color.Model = color.ModelFunc(lchModel)
+	OKLabModel color.Model = color.ModelFunc(oklabModel)
+	OKLCHModel color.Model = color.ModelFunc(oklchModel)
+)
+
+// HSL is a colour in the sRGB cylinder of hue, saturation and lightness. H is in
+// degrees from 0 to 360; S and L are from 0 to 1. The hue of a grey is 0.
+type HSL struct {
+	H, S, L, Alpha float64
+}
+
+// HSV is a colour in the sRGB cylinder of hue, saturation and value. H is in
+// degrees from 0 to 360; S and V are from 0 to 1. The hue of a grey is 0.
+type HSV struct {
+	H, S, V, Alpha float64
+}
+
+// XYZ is a colour in CIE 1931 XYZ, scaled so that Y is 1 for white.
+type XYZ struct {
+	X, Y, Z, Alpha float64
+}
+
+// Lab is a colour in CIELAB. L is from 0 to 100; A and B are unbounded, but are
+// within about ±128 for colours in the sRGB gamut.
+type Lab struct {
+	L, A, B, Alpha float64
+}
+
+// LCh is a CIELAB colour in polar form: lightness, chroma, and hue in degrees
+// from 0 to 360.
+type LCh struct {
+	L, C, H, Alpha float64
+}
+
+// OKLab is a colour in Björn Ottosson's Oklab perceptual colour space. L is from
+// 0 to 1; A and B are within about ±0.4 for colours in the sRGB gamut.
+type OKLab struct {
+	L, A, B, Alpha float64
+}
+
+// OKLCH is an Oklab colour in polar form: lightness, chroma, and hue in degrees
+// from 0 to 360.
+type OKLCH struct {
+	L, C, H, Alpha float64
+}
+
+// srgbColor is implemented by every type in this file, so they can convert
+// between each other without going through 16-bit RGBA.
+type srgbColor interface {
+	// srgb returns the colour as non-premultiplied, gamma-encoded sRGB. The
+	// channels may be out of range.
+	srgb() (r, g, b, a float64)
+}
+
+// srgbFromColor returns c as non-premultiplied, gamma-encoded sRGB in [0, 1].
+func srgbFromColor(c color.Color) (r, g, b, a float64) {
+	switch c := c.(type) {
+	case srgbColor:
+		return c.srgb()
+	case color.NRGBA:
+		return float64(c.R) / 0xff, float64(c.G) / 0xff, float64(c.B) / 0xff, float64(c.A) / 0xff
+	case color.NRGBA64:
+		return float64(c.R) / 0xffff, float64(c.G) / 0xffff, float64(c.B) / 0xffff, float64(c.A) / 0xffff
+	}
+	r16, g16, b16, a16 := c.RGBA()
+	if a16 == 0 {
+		return 0, 0, 0, 0
+	}
+	fa := float64(a16)
+	return float64(r16) / fa, float64(g16) / fa, float64(b16) / fa, fa / 0xffff
+}
+
+// rgba64FromSRGB premultiplies non-premultiplied sRGB, clamping it to [0, 1].
+func rgba64FromSRGB(r, g, b, a float64) (r16, g16, b16, a16 uint32) {
+	a = clampUnit(a)
+	return uint32(math.Round(clampUnit(r) * a * 0xffff)),
+		uint32(math.Round(clampUnit(g) * a * 0xffff)),
+		uint32(math.Round(clampUnit(b) * a * 0xffff)),
+		uint32(math.Round(a * 0xffff))
+}
+
+func nrgbaFromSRGB(r, g, b, a float64) color.NRGBA {
+	return color.NRGBA{
+		R: uint8(math.Round(clampUnit(r) * 0xff)),
+		G: uint8(math.Round(clampUnit(g) * 0xff)),
+		B: uint8(math.Round(clampUnit(b) * 0xff)),
+		A: uint8(math.Round(clampUnit(a) * 0xff)),
+	}
+}
+
+func clampUnit(v float64) float64 {
+	if v < 0 || v != v {
+		return 0
+	} else if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// normHue wraps a hue in degrees into [0, 360).
+func normHue(h float64) float64 {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// rgbHue returns the hue in degrees shared by HSL and HSV, given the largest and
+// smallest channels.
+func rgbHue(r, g, b, max, min float64) float64 {
+	d := max - min
+	if d == 0 {
+		return 0
+	}
+	var h float64
+	switch max {
+	case r:
+		h = (g - b) / d
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return normHue(h * 60)
+}
+
+func hslFromSRGB(r, g, b, a float64) HSL {
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l := (max + min) / 2
+	var s float64
+	if d := max - min; d != 0 {
+		s = d / (1 - math.Abs(2*l-1))
+	}
+	return HSL{H: rgbHue(r, g, b, max, min), S: s, L: l, Alpha: a}
+}
+
+func (c HSL) srgb() (r, g, b, a float64) {
+	r, g, b = hslToRGB(normHue(c.H), c.S, c.L)
+	return r, g, b, c.Alpha
+}
+
+func (c HSL) RGBA() (r, g, b, a uint32) { return rgba64FromSRGB(c.srgb()) }
+func (c HSL) NRGBA() color.NRGBA        { return nrgbaFromSRGB(c.srgb()) }
+
+func hslModel(c color.Color) color.Color {
+	if c, ok := c.(HSL); ok {
+		return c
+	}
+	return hslFromSRGB(srgbFromColor(c))
+}
+
+func hsvFromSRGB(r, g, b, a float64) HSV {
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	var s float64
+	if max != 0 {
+		s = (max - min) / max
+	}
+	return HSV{H: rgbHue(r, g, b, max, min), S: s, V: max, Alpha: a}
+}
+
+func (c HSV) srgb() (r, g, b, a float64) {
+	f := func(n float64) float64 {
+		k := math.Mod(n+normHue(c.H)/60, 6)
+		return c.V - c.V*c.S*math.Max(0, math.Min(math.Min(k, 4-k), 1))
+	}
+	return f(5), f(3), f(1), c.Alpha
+}
+
+func (c HSV) RGBA() (r, g, b, a uint32) { return rgba64FromSRGB(c.srgb()) }
+func (c HSV) NRGBA() color.NRGBA        { return nrgbaFromSRGB(c.srgb()) }
+
+func hsvModel(c color.Color) color.Color {
+	if c, ok := c.(HSV); ok {
+		return c
+	}
+	return hsvFromSRGB(srgbFromColor(c))
+}
+
+var xyzToLinearRGB = linearRGBToXYZ.inverse()
+
+func xyzFromSRGB(r, g, b, a float64) XYZ {
+	x, y, z := linearRGBToXYZ.mul(srgbToLinear(r), srgbToLinear(g), srgbToLinear(b))
+	return XYZ{X: x, Y: y, Z: z, Alpha: a}
+}
+
+func (c XYZ) srgb() (r, g, b, a float64) {
+	r, g, b = xyzToLinearRGB.mul(c.X, c.Y, c.Z)
+	return linearToSRGB(r), linearToSRGB(g), linearToSRGB(b), c.Alpha
+}
+
+func (c XYZ) RGBA() (r, g, b, a uint32) { return rgba64FromSRGB(c.srgb()) }
+func (c XYZ) NRGBA() color.NRGBA        { return nrgbaFromSRGB(c.srgb()) }
+
+func xyzModel(c color.Color) color.Color {
+	if c, ok := c.(XYZ); ok {
+		return c
+	}
+	return xyzFromSRGB(srgbFromColor(c))
+}
+
+func labFromSRGB(r, g, b, a float64) Lab {
+	xyz := xyzFromSRGB(r, g, b, a)
+	l, la, lb := labFromXYZ(xyz.X, xyz.Y, xyz.Z)
+	return Lab{L: l, A: la, B: lb, Alpha: a}
+}
+
+func (c Lab) srgb() (r, g, b, a float64) {
+	x, y, z := labToXYZ(c.L, c.A, c.B)
+	return XYZ{X: x, Y: y, Z: z, Alpha: c.Alpha}.srgb()
+}
+
+func (c Lab) RGBA() (r, g, b, a uint32) { return rgba64FromSRGB(c.srgb()) }
+func (c Lab) NRGBA() color.NRGBA        { return nrgbaFromSRGB(c.srgb()) }
+
+func labModel(c color.Color) color.Color {
+	if c, ok := c.(Lab); ok {
+		return c
+	}
+	return labFromSRGB(srgbFromColor(c))
+}
+
+func lchFromSRGB(r, g, b, a float64) LCh {
+	lab := labFromSRGB(r, g, b, a)
+	return LCh{L: lab.L, C: math.Hypot(lab.A, lab.B), H: labHue(lab.A, lab.B), Alpha: a}
+}
+
+func (c LCh) srgb() (r, g, b, a float64) {
+	sin, cos := math.Sincos(c.H * math.Pi / 180)
+	return Lab{L: c.L, A: c.C * cos, B: c.C * sin, Alpha: c.Alpha}.srgb()
+}
+
+func (c LCh) RGBA() (r, g, b, a uint32) { return rgba64FromSRGB(c.srgb()) }
+func (c LCh) NRGBA() color.NRGBA        { return nrgbaFromSRGB(c.srgb()) }
+
+func lchModel(c color.Color) color.Color {
+	if c, ok := c.(LCh); ok {
+		return c
+	}
+	return lchFromSRGB(srgbFromColor(c))
+}
+
+// Matrices from "A perceptual color space for image processing", Björn Ottosson
+// (2020). linearRGBToLMS already includes the conversion from sRGB to XYZ.
+var (
+	linearRGBToLMS = mat3{
+		0.4122214708, 0.5363325363, 0.0514459929,
+		0.2119034982, 0.6806995451, 0.1073969566,
+		0.0883024619, 0.2817188376, 0.6299787005,
+	}
+	lmsToOKLab = mat3{
+		0.2104542553, 0.7936177850, -0.0040720468,
+		1.9779984951, -2.4285922050, 0.4505937099,
+		0.0259040371, 0.7827717662, -0.8086757660,
+	}
+	lmsToLinearRGB = linearRGBToLMS.inverse()
+	oklabToLMS     = lmsToOKLab.inverse()
+)
+
+func oklabFromSRGB(r, g, b, a float64) OKLab {
+	l, m, s := linearRGBToLMS.mul(srgbToLinear(r), srgbToLinear(g), srgbToLinear(b))
+	ll, la, lb := lmsToOKLab.mul(math.Cbrt(l), math.Cbrt(m), math.Cbrt(s))
+	return OKLab{L: ll, A: la, B: lb, Alpha: a}
+}
+
+func (c OKLab) srgb() (r, g, b, a float64) {
+	l, m, s := oklabToLMS.mul(c.L, c.A, c.B)
+	r, g, b = lmsToLinearRGB.mul(l*l*l, m*m*m, s*s*s)
+	return linearToSRGB(r), linearToSRGB(g), linearToSRGB(b), c.Alpha
+}
+
+func (c OKLab) RGBA() (r, g, b, a uint32) { return rgba64FromSRGB(c.srgb()) }
+func (c OKLab) NRGBA() color.NRGBA        { return nrgbaFromSRGB(c.srgb()) }
+
+func oklabModel(c color.Color) color.Color {
+	if c, ok := c.(OKLab); ok {
+		return c
+	}
+	return oklabFromSRGB(srgbFromColor(c))
+}
+
+func oklchFromSRGB(r, g, b, a float64) OKLCH {
+	lab := oklabFromSRGB(r, g, b, a)
+	return OKLCH{L: lab.L, C: math.Hypot(lab.A, lab.B), H: labHue(lab.A, lab.B), Alpha: a}
+}
+
+func (c OKLCH) srgb() (r, g, b, a float64) {
+	sin, cos := math.Sincos(c.H * math.Pi / 180)
+	return OKLab{L: c.L, A: c.C * cos, B: c.C * sin, Alpha: c.Alpha}.srgb()
+}
+
+func (c OKLCH) RGBA() (r, g, b, a uint32) { return rgba64FromSRGB(c.srgb()) }
+func (c OKLCH) NRGBA() color.NRGBA        { return nrgbaFromSRGB(c.srgb()) }
+
+func oklchModel(c color.Color) color.Color {
+	if c, ok := c.(OKLCH); ok {
+		return c
+	}
+	return oklchFromSRGB(srgbFromColor(c))
+}
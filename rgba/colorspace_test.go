@@ -0,0 +1,164 @@
+package rgba
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+var colorspaceModels = []struct {
+	name  string
+	model color.Model
+}{
+	{"hsl", HSLModel},
+	{"hsv", HSVModel},
+	{"xyz", XYZModel},
+	{"lab", LabModel},
+	{"lch", LChModel},
+	{"oklab", OKLabModel},
+	{"oklch", OKLCHModel},
+}
+
+func TestColorspaceRGBARoundTrip(t *testing.T) {
+	for _, m := range colorspaceModels {
+		t.Run(m.name, func(t *testing.T) {
+			rng := testimg.NewRNG(0)
+			for i := 0; i < 20000; i++ {
+				c := testimg.RandRGBA(rng)
+				conv := m.model.Convert(c)
+				if back := color.RGBAModel.Convert(conv).(color.RGBA); back != c {
+					t.Fatalf("expected %v, found %v via %v", c, back, conv)
+				}
+				if again := m.model.Convert(conv); again != conv {
+					t.Fatalf("expected %v to convert to itself, found %v", conv, again)
+				}
+			}
+		})
+	}
+}
+
+func TestColorspaceNRGBARoundTrip(t *testing.T) {
+	for _, m := range colorspaceModels {
+		t.Run(m.name, func(t *testing.T) {
+			rng := testimg.NewRNG(0)
+			for i := 0; i < 20000; i++ {
+				c := color.NRGBA{uint8(rng.Uint64()), uint8(rng.Uint64()), uint8(rng.Uint64()), uint8(rng.Uint64())}
+				conv := m.model.Convert(c)
+				back := conv.(interface{ NRGBA() color.NRGBA }).NRGBA()
+				if back != c {
+					t.Fatalf("expected %v, found %v via %v", c, back, conv)
+				}
+			}
+		})
+	}
+}
+
+func TestColorspaceBetweenModels(t *testing.T) {
+	// Converting between two of our own types must not lose precision to 16-bit
+	// RGBA along the way:
+	rng := testimg.NewRNG(0)
+	for i := 0; i < 1000; i++ {
+		c := color.NRGBA{uint8(rng.Uint64()), uint8(rng.Uint64()), uint8(rng.Uint64()), 0xff}
+		for _, from := range colorspaceModels {
+			for _, to := range colorspaceModels {
+				conv := to.model.Convert(from.model.Convert(c))
+				if back := conv.(interface{ NRGBA() color.NRGBA }).NRGBA(); back != c {
+					t.Fatalf("%s to %s: expected %v, found %v", from.name, to.name, c, back)
+				}
+			}
+		}
+	}
+}
+
+func TestColorspaceKnown(t *testing.T) {
+	red := color.NRGBA{0xff, 0, 0, 0xff}
+	white := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	for idx, tc := range []struct {
+		in   color.NRGBA
+		out  color.Color
+		prec float64
+	}{
+		{red, HSL{0, 1, 0.5, 1}, 1e-12},
+		{red, HSV{0, 1, 1, 1}, 1e-12},
+		{color.NRGBA{0, 0x80, 0, 0xff}, HSV{120, 1, 128.0 / 255, 1}, 1e-12},
+		{white, XYZ{0.9505, 1, 1.089, 1}, 1e-3},
+		{red, XYZ{0.4125, 0.2127, 0.0193, 1}, 1e-4},
+		{white, Lab{100, 0, 0, 1}, 1e-2},
+		{red, Lab{53.24, 80.09, 67.20, 1}, 1e-2},
+		{red, LCh{53.24, 104.55, 40.0, 1}, 1e-2},
+		{white, OKLab{1, 0, 0, 1}, 1e-4},
+		{red, OKLab{0.6280, 0.2249, 0.1258, 1}, 1e-4},
+		{red, OKLCH{0.6280, 0.2577, 29.23, 1}, 1e-2},
+		{color.NRGBA{0, 0, 0, 0}, OKLab{0, 0, 0, 0}, 1e-12},
+	} {
+		t.Run(fmt.Sprint(idx), func(t *testing.T) {
+			var found color.Color
+			switch tc.out.(type) {
+			case HSL:
+				found = HSLModel.Convert(tc.in)
+			case HSV:
+				found = HSVModel.Convert(tc.in)
+			case XYZ:
+				found = XYZModel.Convert(tc.in)
+			case Lab:
+				found = LabModel.Convert(tc.in)
+			case LCh:
+				found = LChModel.Convert(tc.in)
+			case OKLab:
+				found = OKLabModel.Convert(tc.in)
+			case OKLCH:
+				found = OKLCHModel.Convert(tc.in)
+			}
+			fv, ev := colorspaceFields(found), colorspaceFields(tc.out)
+			for i := range ev {
+				if math.Abs(fv[i]-ev[i]) > tc.prec {
+					t.Fatalf("expected %v, found %v", tc.out, found)
+				}
+			}
+		})
+	}
+}
+
+func TestColorspaceOutOfGamut(t *testing.T) {
+	for _, tc := range []struct {
+		in  interface{ NRGBA() color.NRGBA }
+		out color.NRGBA
+	}{
+		{HSL{-120, 1, 0.5, 1}, color.NRGBA{0, 0, 0xff, 0xff}},
+		{HSV{480, 1, 1, 2}, color.NRGBA{0, 0xff, 0, 0xff}},
+		{Lab{200, 0, 0, 1}, color.NRGBA{0xff, 0xff, 0xff, 0xff}},
+		{OKLab{-1, 0, 0, -1}, color.NRGBA{}},
+		{OKLCH{0.7, 2, 0, 1}, color.NRGBA{0xff, 0, 0x6a, 0xff}},
+	} {
+		if found := tc.in.NRGBA(); found != tc.out {
+			t.Fatalf("%v: expected %v, found %v", tc.in, tc.out, found)
+		}
+		r, g, b, a := tc.in.(color.Color).RGBA()
+		if r > a || g > a || b > a || a > 0xffff {
+			t.Fatalf("%v: invalid premultiplied colour %d %d %d %d", tc.in, r, g, b, a)
+		}
+	}
+}
+
+func colorspaceFields(c color.Color) [4]float64 {
+	switch c := c.(type) {
+	case HSL:
+		return [4]float64{c.H, c.S, c.L, c.Alpha}
+	case HSV:
+		return [4]float64{c.H, c.S, c.V, c.Alpha}
+	case XYZ:
+		return [4]float64{c.X, c.Y, c.Z, c.Alpha}
+	case Lab:
+		return [4]float64{c.L, c.A, c.B, c.Alpha}
+	case LCh:
+		return [4]float64{c.L, c.C, c.H, c.Alpha}
+	case OKLab:
+		return [4]float64{c.L, c.A, c.B, c.Alpha}
+	case OKLCH:
+		return [4]float64{c.L, c.C, c.H, c.Alpha}
+	}
+	panic(fmt.Sprintf("rgba: unexpected colour %T", c))
+}
@@ -0,0 +1,357 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// The functions in this file compare two images of the same size, which is handy
+// for regression tests that need a tolerance rather than exact equality. They all
+// panic if the images are not the same size.
+
+func compareMustMatch(a, b *Image) {
+	if a.Size != b.Size {
+		panic("rgba: image sizes do not match")
+	}
+}
+
+// MSE returns the mean squared error per channel between a and b, across all four
+// channels.
+func MSE(a, b *Image) float64 {
+	compareMustMatch(a, b)
+	n := a.Size.X * a.Size.Y
+	if n == 0 {
+		return 0
+	}
+
+	var sum uint64
+	for y := 0; y < a.Size.Y; y++ {
+		arow := a.Vals[y*a.Stride : y*a.Stride+a.Size.X]
+		brow := b.Vals[y*b.Stride : y*b.Stride+b.Size.X]
+		for x, ac := range arow {
+			sum += uint64(MetricRGBA(ac, brow[x]))
+		}
+	}
+	return float64(sum) / float64(n*4)
+}
+
+// PSNR returns the peak signal-to-noise ratio between a and b in dB, derived from
+// MSE. If the images are identical, the result is +Inf.
+func PSNR(a, b *Image) float64 {
+	mse := MSE(a, b)
+	if mse == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(255*255/mse)
+}
+
+// DeltaReport describes the largest per-channel differences between two images.
+// See MaxDelta.
+type DeltaReport struct {
+	// Max is the largest absolute difference found in each channel, in R, G, B, A
+	// order.
+	Max [4]uint8
+
+	// MaxAt is the location of the first pixel with the largest difference in any
+	// channel.
+	MaxAt image.Point
+
+	// Mismatched is the number of pixels where any channel differs by more than
+	// the tolerance passed to MaxDelta.
+	Mismatched int
+}
+
+// Channel returns the largest difference found in any channel.
+func (d DeltaReport) Channel() uint8 {
+	m := d.Max[0]
+	for _, v := range d.Max[1:] {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+// MaxDelta reports the largest per-channel differences between a and b. Pixels
+// where any channel differs by more than tolerance are counted as mismatched.
+func MaxDelta(a, b *Image, tolerance uint8) (d DeltaReport) {
+	compareMustMatch(a, b)
+
+	var best uint8
+	for y := 0; y < a.Size.Y; y++ {
+		arow := a.Vals[y*a.Stride : y*a.Stride+a.Size.X]
+		brow := b.Vals[y*b.Stride : y*b.Stride+b.Size.X]
+		for x, ac := range arow {
+			bc := brow[x]
+			if ac == bc {
+				continue
+			}
+			deltas := [4]uint8{
+				absDiff8(ac.R, bc.R),
+				absDiff8(ac.G, bc.G),
+				absDiff8(ac.B, bc.B),
+				absDiff8(ac.A, bc.A),
+			}
+			var mismatch bool
+			for i, v := range deltas {
+				if v > d.Max[i] {
+					d.Max[i] = v
+				}
+				if v > best {
+					best, d.MaxAt = v, image.Pt(x, y)
+				}
+				mismatch = mismatch || v > tolerance
+			}
+			if mismatch {
+				d.Mismatched++
+			}
+		}
+	}
+	return d
+}
+
+// DiffImage returns an image that highlights the differences between a and b.
+// Pixels where any channel differs by more than tolerance are drawn in red, with
+// brightness proportional to the difference; all other pixels are drawn as a faded
+// greyscale copy of a, for context.
+//
+// testimg.DiffImage draws the same image for any pair of image.Images.
+//
+func DiffImage(a, b *Image, tolerance uint8) *Image {
+	compareMustMatch(a, b)
+
+	out := New(a.Size)
+	for y := 0; y < a.Size.Y; y++ {
+		arow := a.Vals[y*a.Stride : y*a.Stride+a.Size.X]
+		brow := b.Vals[y*b.Stride : y*b.Stride+b.Size.X]
+		orow := out.Vals[y*out.Stride:]
+		for x, ac := range arow {
+			bc := brow[x]
+			m := absDiff8(ac.R, bc.R)
+			for _, v := range []uint8{absDiff8(ac.G, bc.G), absDiff8(ac.B, bc.B), absDiff8(ac.A, bc.A)} {
+				if v > m {
+					m = v
+				}
+			}
+
+			if m > tolerance {
+				// Scale so even a difference of 1 is clearly visible:
+				v := uint8(0x80 + uint32(m)*0x7f/0xff)
+				orow[x] = color.RGBA{R: v, A: 0xff}
+			} else {
+				l := uint8(luma709(ac) / 10000 / 4)
+				orow[x] = color.RGBA{R: 0xbf + l, G: 0xbf + l, B: 0xbf + l, A: 0xff}
+			}
+		}
+	}
+	return out
+}
+
+func absDiff8(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// Constants from "Image Quality Assessment: From Error Visibility to Structural
+// Similarity", Wang, Bovik, Sheikh and Simoncelli (2004).
+const (
+	ssimWindow = 11
+	ssimSigma  = 1.5
+	ssimC1     = (0.01 * 255) * (0.01 * 255)
+	ssimC2     = (0.03 * 255) * (0.03 * 255)
+)
+
+// Weights for each scale from "Multi-scale Structural Similarity for Image Quality
+// Assessment", Wang, Simoncelli and Bovik (2003).
+var msssimWeights = [5]float64{0.0448, 0.2856, 0.3001, 0.2363, 0.1333}
+
+var ssimKernel = func() (k [ssimWindow]float64) {
+	var sum float64
+	for i := range k {
+		d := float64(i - ssimWindow/2)
+		k[i] = math.Exp(-(d * d) / (2 * ssimSigma * ssimSigma))
+		sum += k[i]
+	}
+	for i := range k {
+		k[i] /= sum
+	}
+	return k
+}()
+
+// SSIM returns the mean structural similarity index between the luma of a and b,
+// using an 11x11 Gaussian window. 1 means the images are identical. Alpha is
+// ignored; premultiplied colours are treated as if composited over black.
+//
+// Images smaller than the window in either dimension are compared using a single
+// window covering the whole image.
+//
+func SSIM(a, b *Image) float64 {
+	compareMustMatch(a, b)
+	la, lb := ssimLuma(a), ssimLuma(b)
+	ssim, _ := ssimPlane(la, lb, a.Size.X, a.Size.Y)
+	return ssim
+}
+
+// MSSSIM returns the multi-scale structural similarity index between the luma of a
+// and b, using five scales. If the image is too small for five scales, as many as
+// fit are used, and the weights are renormalised.
+//
+// See SSIM.
+//
+func MSSSIM(a, b *Image) float64 {
+	compareMustMatch(a, b)
+	la, lb := ssimLuma(a), ssimLuma(b)
+	w, h := a.Size.X, a.Size.Y
+
+	scales := 1
+	for sw, sh := w, h; scales < len(msssimWeights) && sw/2 >= ssimWindow && sh/2 >= ssimWindow; scales++ {
+		sw, sh = sw/2, sh/2
+	}
+
+	var wsum float64
+	for _, wt := range msssimWeights[:scales] {
+		wsum += wt
+	}
+
+	result := 1.0
+	for s := 0; s < scales; s++ {
+		ssim, cs := ssimPlane(la, lb, w, h)
+		wt := msssimWeights[s] / wsum
+		v := cs
+		if s == scales-1 {
+			v = ssim
+		}
+		if v < 0 {
+			v = 0
+		}
+		result *= math.Pow(v, wt)
+
+		if s < scales-1 {
+			la, _, _ = ssimDownsample(la, w, h)
+			lb, w, h = ssimDownsample(lb, w, h)
+		}
+	}
+	return result
+}
+
+func ssimLuma(img *Image) []float64 {
+	out := make([]float64, img.Size.X*img.Size.Y)
+	i := 0
+	for y := 0; y < img.Size.Y; y++ {
+		for _, c := range img.Vals[y*img.Stride : y*img.Stride+img.Size.X] {
+			out[i] = float64(luma709(c)) / 10000
+			i++
+		}
+	}
+	return out
+}
+
+func ssimDownsample(in []float64, w, h int) (out []float64, ow, oh int) {
+	ow, oh = w/2, h/2
+	out = make([]float64, ow*oh)
+	for y := 0; y < oh; y++ {
+		for x := 0; x < ow; x++ {
+			i := (y*2)*w + x*2
+			out[y*ow+x] = (in[i] + in[i+1] + in[i+w] + in[i+w+1]) / 4
+		}
+	}
+	return out, ow, oh
+}
+
+// ssimPlane returns the mean SSIM and mean contrast-structure term over all valid
+// window positions.
+func ssimPlane(a, b []float64, w, h int) (ssim, cs float64) {
+	if w == 0 || h == 0 {
+		return 1, 1
+	}
+	if w < ssimWindow || h < ssimWindow {
+		return ssimWhole(a, b)
+	}
+
+	xy := make([]float64, len(a))
+	aa := make([]float64, len(a))
+	bb := make([]float64, len(a))
+	for i := range a {
+		xy[i] = a[i] * b[i]
+		aa[i] = a[i] * a[i]
+		bb[i] = b[i] * b[i]
+	}
+
+	muA, ow, oh := ssimFilter(a, w, h)
+	muB, _, _ := ssimFilter(b, w, h)
+	sAA, _, _ := ssimFilter(aa, w, h)
+	sBB, _, _ := ssimFilter(bb, w, h)
+	sAB, _, _ := ssimFilter(xy, w, h)
+
+	for i := range muA {
+		ma, mb := muA[i], muB[i]
+		varA := sAA[i] - ma*ma
+		varB := sBB[i] - mb*mb
+		cov := sAB[i] - ma*mb
+
+		c := (2*cov + ssimC2) / (varA + varB + ssimC2)
+		l := (2*ma*mb + ssimC1) / (ma*ma + mb*mb + ssimC1)
+		cs += c
+		ssim += l * c
+	}
+
+	n := float64(ow * oh)
+	return ssim / n, cs / n
+}
+
+// ssimWhole computes SSIM using a single, unweighted window covering everything.
+func ssimWhole(a, b []float64) (ssim, cs float64) {
+	n := float64(len(a))
+	var ma, mb float64
+	for i := range a {
+		ma += a[i]
+		mb += b[i]
+	}
+	ma, mb = ma/n, mb/n
+
+	var varA, varB, cov float64
+	for i := range a {
+		da, db := a[i]-ma, b[i]-mb
+		varA += da * da
+		varB += db * db
+		cov += da * db
+	}
+	varA, varB, cov = varA/n, varB/n, cov/n
+
+	cs = (2*cov + ssimC2) / (varA + varB + ssimC2)
+	l := (2*ma*mb + ssimC1) / (ma*ma + mb*mb + ssimC1)
+	return l * cs, cs
+}
+
+// ssimFilter applies the Gaussian window to every position where it fits entirely
+// inside the plane.
+func ssimFilter(in []float64, w, h int) (out []float64, ow, oh int) {
+	ow, oh = w-ssimWindow+1, h-ssimWindow+1
+
+	horiz := make([]float64, ow*h)
+	for y := 0; y < h; y++ {
+		row := in[y*w : y*w+w]
+		for x := 0; x < ow; x++ {
+			var sum float64
+			for k, kv := range ssimKernel {
+				sum += row[x+k] * kv
+			}
+			horiz[y*ow+x] = sum
+		}
+	}
+
+	out = make([]float64, ow*oh)
+	for y := 0; y < oh; y++ {
+		for x := 0; x < ow; x++ {
+			var sum float64
+			for k, kv := range ssimKernel {
+				sum += horiz[(y+k)*ow+x] * kv
+			}
+			out[y*ow+x] = sum
+		}
+	}
+	return out, ow, oh
+}
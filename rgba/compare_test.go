@@ -0,0 +1,184 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func TestCompareIdentical(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for _, sz := range []image.Point{{1, 1}, {7, 5}, {64, 48}, {200, 200}} {
+		gen := testimg.RandBlocks{W: sz.X, H: sz.Y, BlockW: 4, BlockH: 4}
+		img, _ := Convert(gen.RGBA(rng))
+
+		if mse := MSE(img, img); mse != 0 {
+			t.Fatal(sz, mse)
+		}
+		if psnr := PSNR(img, img); !math.IsInf(psnr, 1) {
+			t.Fatal(sz, psnr)
+		}
+		if ssim := SSIM(img, img); math.Abs(ssim-1) > 1e-9 {
+			t.Fatal(sz, ssim)
+		}
+		if ms := MSSSIM(img, img); math.Abs(ms-1) > 1e-9 {
+			t.Fatal(sz, ms)
+		}
+		if d := MaxDelta(img, img, 0); d != (DeltaReport{}) {
+			t.Fatal(sz, d)
+		}
+	}
+}
+
+func TestCompareDifferent(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	gen := testimg.RandBlocks{W: 200, H: 200, BlockW: 8, BlockH: 8}
+	a, _ := Convert(gen.RGBA(rng))
+
+	// Small noise should score better than large noise on every metric:
+	noisy := func(amp int) *Image {
+		out := New(a.Size)
+		for i, c := range a.Vals {
+			j := func(v uint8) uint8 {
+				n := int(v) + rng.Intn(amp*2+1) - amp
+				if n < 0 {
+					n = 0
+				} else if n > 0xff {
+					n = 0xff
+				}
+				return uint8(n)
+			}
+			out.Vals[i] = color.RGBA{j(c.R), j(c.G), j(c.B), c.A}
+		}
+		return out
+	}
+	small, large := noisy(4), noisy(64)
+
+	if !(MSE(a, small) < MSE(a, large)) || !(PSNR(a, small) > PSNR(a, large)) {
+		t.Fatal("mse/psnr")
+	}
+	ssimSmall, ssimLarge := SSIM(a, small), SSIM(a, large)
+	if !(ssimSmall > ssimLarge) || ssimSmall >= 1 || ssimLarge <= -1 {
+		t.Fatal("ssim", ssimSmall, ssimLarge)
+	}
+	msSmall, msLarge := MSSSIM(a, small), MSSSIM(a, large)
+	if !(msSmall > msLarge) || msSmall >= 1 || msLarge < 0 {
+		t.Fatal("msssim", msSmall, msLarge)
+	}
+
+	d := MaxDelta(a, small, 4)
+	if d.Mismatched != 0 || d.Channel() == 0 || d.Channel() > 4 || d.Max[3] != 0 {
+		t.Fatal(d)
+	}
+}
+
+func TestSSIMReference(t *testing.T) {
+	grey := func(size image.Point, at func(x, y int) int) *Image {
+		img := New(size)
+		for y := 0; y < size.Y; y++ {
+			for x := 0; x < size.X; x++ {
+				v := uint8(at(x, y))
+				img.SetRGBA(x, y, color.RGBA{v, v, v, 0xff})
+			}
+		}
+		return img
+	}
+
+	// For flat images, the variances and covariance are zero, so SSIM is just the
+	// luminance term, (2*a*b + C1) / (a*a + b*b + C1):
+	for _, size := range []image.Point{{4, 4}, {32, 32}} {
+		a := grey(size, func(x, y int) int { return 100 })
+		b := grey(size, func(x, y int) int { return 150 })
+		want := (2*100*150 + ssimC1) / (100*100 + 150*150 + ssimC1)
+		if found := SSIM(a, b); math.Abs(found-want) > 1e-9 {
+			t.Fatalf("%v: expected %v, found %v", size, want, found)
+		}
+	}
+
+	// Reference values computed separately, by a direct (not separable) 11x11
+	// Gaussian window over every valid position, with MS-SSIM using two scales:
+	aAt := func(x, y int) int { return (x*37 + y*11 + (x*y)%17) % 256 }
+	bAt := func(x, y int) int {
+		v := aAt(x, y)*3/4 + 40 + (x*5+y*3)%23 - 11
+		if v < 0 {
+			return 0
+		} else if v > 0xff {
+			return 0xff
+		}
+		return v
+	}
+	a, b := grey(image.Pt(24, 24), aAt), grey(image.Pt(24, 24), bAt)
+	if found, want := SSIM(a, b), 0.9499729974089314; math.Abs(found-want) > 1e-9 {
+		t.Fatalf("ssim: expected %v, found %v", want, found)
+	}
+	if found, want := MSSSIM(a, b), 0.9547346477013291; math.Abs(found-want) > 1e-9 {
+		t.Fatalf("msssim: expected %v, found %v", want, found)
+	}
+}
+
+func TestMaxDelta(t *testing.T) {
+	a := New(image.Pt(4, 3))
+	b := New(image.Pt(4, 3))
+	b.SetRGBA(1, 1, color.RGBA{R: 3})
+	b.SetRGBA(2, 2, color.RGBA{G: 10, A: 2})
+	b.SetRGBA(3, 2, color.RGBA{B: 10})
+
+	d := MaxDelta(a, b, 3)
+	if d.Max != [4]uint8{3, 10, 10, 2} {
+		t.Fatal(d)
+	}
+	if d.MaxAt != image.Pt(2, 2) || d.Mismatched != 2 {
+		t.Fatal(d)
+	}
+
+	diff := DiffImage(a, b, 3)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			c := diff.RGBAAt(x, y)
+			mismatched := (x == 2 || x == 3) && y == 2
+			if red := c.G == 0 && c.R >= 0x80; red != mismatched {
+				t.Fatal(x, y, c)
+			}
+		}
+	}
+}
+
+func TestDiffImageMatchesTestimg(t *testing.T) {
+	rng := testimg.NewRNG(0)
+	a, _ := Convert(testimg.Plasma{W: 32, H: 16}.RGBA(rng))
+	b, _ := Convert(testimg.WhiteNoise{W: 32, H: 16, Alpha: true}.RGBA(rng))
+	for _, tolerance := range []uint8{0, 64, 255} {
+		testimg.AssertImage(t, DiffImage(a, b, tolerance), testimg.DiffImage(a, b, tolerance), 0)
+	}
+}
+
+func TestCompareSizeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	MSE(New(image.Pt(2, 2)), New(image.Pt(2, 3)))
+}
+
+func BenchmarkSSIM(b *testing.B) {
+	rng := rand.New(rand.NewSource(0))
+	gen := testimg.RandBlocks{W: 256, H: 256, BlockW: 8, BlockH: 8}
+	x, _ := Convert(gen.RGBA(rng))
+	y, _ := Convert(gen.RGBA(rng))
+
+	b.Run("ssim", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			SSIM(x, y)
+		}
+	})
+	b.Run("msssim", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			MSSSIM(x, y)
+		}
+	})
+}
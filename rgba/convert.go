@@ -48,6 +48,8 @@ func Convert(img image.Image) (out *Image, copied bool) {
 	switch img := img.(type) {
 	case *Image:
 		return img, false
+	case *Paletted:
+		return img.Image(), true
 	case *image.CMYK:
 		return convertCMYKToRGBA(img), true
 	case *image.NRGBA:
@@ -72,16 +74,20 @@ func Convert(img image.Image) (out *Image, copied bool) {
 func convertCMYKToRGBA(img *image.CMYK) *Image {
 	size := img.Bounds().Size()
 	out := New(size)
-	inPix, outVals := img.Pix, out.Vals
-
-	for i, j := 0, 0; i < len(inPix); i, j = i+4, j+1 {
-		// Seems like it might be unnecessary to go from 8-bit to 16-bit to
-		// 8-bit again, but I'm not quite sure yet and haven't looked further:
-		w := 0xffff - uint32(inPix[i+3])*0x101
-		outVals[j].R = uint8(((0xffff - uint32(inPix[i+0])*0x101) * w / 0xffff) >> 8)
-		outVals[j].G = uint8(((0xffff - uint32(inPix[i+1])*0x101) * w / 0xffff) >> 8)
-		outVals[j].B = uint8(((0xffff - uint32(inPix[i+2])*0x101) * w / 0xffff) >> 8)
-		outVals[j].A = 0xff
+
+	for y := 0; y < size.Y; y++ {
+		inPix := img.Pix[y*img.Stride : y*img.Stride+size.X*4]
+		outVals := out.Vals[y*out.Stride : y*out.Stride+size.X]
+
+		for i, j := 0, 0; i < len(inPix); i, j = i+4, j+1 {
+			// Seems like it might be unnecessary to go from 8-bit to 16-bit to
+			// 8-bit again, but I'm not quite sure yet and haven't looked further:
+			w := 0xffff - uint32(inPix[i+3])*0x101
+			outVals[j].R = uint8(((0xffff - uint32(inPix[i+0])*0x101) * w / 0xffff) >> 8)
+			outVals[j].G = uint8(((0xffff - uint32(inPix[i+1])*0x101) * w / 0xffff) >> 8)
+			outVals[j].B = uint8(((0xffff - uint32(inPix[i+2])*0x101) * w / 0xffff) >> 8)
+			outVals[j].A = 0xff
+		}
 	}
 
 	return out
@@ -99,9 +105,11 @@ func convertPalettedToRGBA(img *image.Paletted) *Image {
 
 	size := img.Bounds().Size()
 	out := New(size)
-	inPix, outVals := img.Pix, out.Vals
-	for i, c := range inPix {
-		outVals[i] = pal[c]
+	for y := 0; y < size.Y; y++ {
+		outVals := out.Vals[y*out.Stride : y*out.Stride+size.X]
+		for i, c := range img.Pix[y*img.Stride : y*img.Stride+size.X] {
+			outVals[i] = pal[c]
+		}
 	}
 	return out
 }
@@ -111,28 +119,32 @@ func convertYCbCrToRGBA(img *image.YCbCr) *Image {
 	size := bounds.Size()
 	vals := make([]color.RGBA, size.X*size.Y)
 
+	// Chroma offsets are calculated from absolute coordinates, so sub-images whose
+	// origin does not line up with the chroma samples work:
+	mx, my := bounds.Min.X, bounds.Min.Y
+
 	var pix int
 	var accumYOffset int
-	for y := 0; y < size.Y; y++ {
-		for x := 0; x < size.X; x++ {
+	for y := my; y < bounds.Max.Y; y++ {
+		for x := mx; x < bounds.Max.X; x++ {
 			// image:YCbCr.YOffset():
-			var yOffset = accumYOffset + x
+			var yOffset = accumYOffset + (x - mx)
 			var cOffset int
 
 			// {{{ image.YCbCr.COffset():
 			switch img.SubsampleRatio {
 			case image.YCbCrSubsampleRatio422:
-				cOffset = (y)*img.CStride + (x / 2)
+				cOffset = (y-my)*img.CStride + (x/2 - mx/2)
 			case image.YCbCrSubsampleRatio420:
-				cOffset = (y/2)*img.CStride + (x / 2)
+				cOffset = (y/2-my/2)*img.CStride + (x/2 - mx/2)
 			case image.YCbCrSubsampleRatio440:
-				cOffset = (y/2)*img.CStride + (x)
+				cOffset = (y/2-my/2)*img.CStride + (x - mx)
 			case image.YCbCrSubsampleRatio411:
-				cOffset = (y)*img.CStride + (x / 4)
+				cOffset = (y-my)*img.CStride + (x/4 - mx/4)
 			case image.YCbCrSubsampleRatio410:
-				cOffset = (y/2)*img.CStride + (x / 4)
+				cOffset = (y/2-my/2)*img.CStride + (x/4 - mx/4)
 			default:
-				cOffset = (y)*img.CStride + (x)
+				cOffset = (y-my)*img.CStride + (x - mx)
 			}
 			// }}}
 
@@ -177,35 +189,79 @@ func convertYCbCrToRGBA(img *image.YCbCr) *Image {
 func convertNRGBA64ToRGBA(img *image.NRGBA64) *Image {
 	size := img.Bounds().Size()
 	out := New(size)
-	inPix, outVals := img.Pix, out.Vals
-	inLen := len(inPix)
 
-	for ip, op := 0, 0; ip < inLen; ip, op = ip+8, op+1 {
-		a := (uint32(inPix[ip+6]) << 8) | uint32(inPix[ip+7])
+	for y := 0; y < size.Y; y++ {
+		inPix := img.Pix[y*img.Stride : y*img.Stride+size.X*8]
+		outVals := out.Vals[y*out.Stride : y*out.Stride+size.X]
+
+		for ip, op := 0, 0; ip < len(inPix); ip, op = ip+8, op+1 {
+			a := (uint32(inPix[ip+6]) << 8) | uint32(inPix[ip+7])
 
-		outVals[op] = color.RGBA{
-			R: uint8(((uint32(inPix[ip+0]) << 8) | uint32(inPix[ip+1])*a/0xffff) >> 8),
-			G: uint8(((uint32(inPix[ip+2]) << 8) | uint32(inPix[ip+3])*a/0xffff) >> 8),
-			B: uint8(((uint32(inPix[ip+4]) << 8) | uint32(inPix[ip+5])*a/0xffff) >> 8),
-			A: uint8(a >> 8),
+			outVals[op] = color.RGBA{
+				R: uint8((((uint32(inPix[ip+0]) << 8) | uint32(inPix[ip+1])) * a / 0xffff) >> 8),
+				G: uint8((((uint32(inPix[ip+2]) << 8) | uint32(inPix[ip+3])) * a / 0xffff) >> 8),
+				B: uint8((((uint32(inPix[ip+4]) << 8) | uint32(inPix[ip+5])) * a / 0xffff) >> 8),
+				A: uint8(a >> 8),
+			}
 		}
 	}
 
 	return out
 }
 
+// colorToRGBA converts any colour to premultiplied RGBA, with fast paths for the
+// common colour types.
+func colorToRGBA(c color.Color) color.RGBA {
+	switch c := c.(type) {
+	case color.RGBA:
+		return c
+
+	case color.RGBA64:
+		return color.RGBA{
+			R: uint8(c.R >> 8),
+			G: uint8(c.G >> 8),
+			B: uint8(c.B >> 8),
+			A: uint8(c.A >> 8),
+		}
+
+	case color.NRGBA:
+		return premultiplyNRGBA(c)
+
+	default:
+		r, g, b, a := c.RGBA()
+		return color.RGBA{
+			R: uint8(r >> 8),
+			G: uint8(g >> 8),
+			B: uint8(b >> 8),
+			A: uint8(a >> 8),
+		}
+	}
+}
+
+// premultiplyNRGBA converts c to premultiplied RGBA with the same rounding as
+// color.NRGBA, which premultiplies at 16 bits before truncating back to 8:
+// (x*0x101)*(a*0x101)/0xffff == x*a*0x101/0xff. Every NRGBA conversion in this
+// package goes through here so they all agree.
+func premultiplyNRGBA(c color.NRGBA) color.RGBA {
+	a := uint32(c.A)
+	return color.RGBA{
+		R: uint8((uint32(c.R) * a * 0x101 / 0xff) >> 8),
+		G: uint8((uint32(c.G) * a * 0x101 / 0xff) >> 8),
+		B: uint8((uint32(c.B) * a * 0x101 / 0xff) >> 8),
+		A: c.A,
+	}
+}
+
 func convertNRGBAToRGBA(img *image.NRGBA) *Image {
 	size := img.Bounds().Size()
 	out := New(size)
-	inPix, outVals := img.Pix, out.Vals
-
-	for ip, op := 0, 0; ip < len(inPix); ip, op = ip+4, op+1 {
-		a := uint32(inPix[ip+3])
-		outVals[op] = color.RGBA{
-			R: uint8(uint32(inPix[ip+0]) * a / 0xff),
-			G: uint8(uint32(inPix[ip+1]) * a / 0xff),
-			B: uint8(uint32(inPix[ip+2]) * a / 0xff),
-			A: uint8(a),
+
+	for y := 0; y < size.Y; y++ {
+		inPix := img.Pix[y*img.Stride : y*img.Stride+size.X*4]
+		outVals := out.Vals[y*out.Stride : y*out.Stride+size.X]
+
+		for ip, op := 0, 0; ip < len(inPix); ip, op = ip+4, op+1 {
+			outVals[op] = premultiplyNRGBA(color.NRGBA{inPix[ip+0], inPix[ip+1], inPix[ip+2], inPix[ip+3]})
 		}
 	}
 
@@ -215,15 +271,19 @@ func convertNRGBAToRGBA(img *image.NRGBA) *Image {
 func convertRGBA64ToRGBA(img *image.RGBA64) *Image {
 	size := img.Bounds().Size()
 	out := New(size)
-	inPix, outVals := img.Pix, out.Vals
-
-	for ip, op := 0, 0; ip < len(inPix); ip, op = ip+8, op+1 {
-		// RGBA64 stores pixels in big-endian pairs. We only need the big end:
-		outVals[op] = color.RGBA{
-			R: inPix[ip+0],
-			G: inPix[ip+2],
-			B: inPix[ip+4],
-			A: inPix[ip+6],
+
+	for y := 0; y < size.Y; y++ {
+		inPix := img.Pix[y*img.Stride : y*img.Stride+size.X*8]
+		outVals := out.Vals[y*out.Stride : y*out.Stride+size.X]
+
+		for ip, op := 0, 0; ip < len(inPix); ip, op = ip+8, op+1 {
+			// RGBA64 stores pixels in big-endian pairs. We only need the big end:
+			outVals[op] = color.RGBA{
+				R: inPix[ip+0],
+				G: inPix[ip+2],
+				B: inPix[ip+4],
+				A: inPix[ip+6],
+			}
 		}
 	}
 
@@ -236,15 +296,18 @@ func convertRGBAToRGBASlow(img *image.RGBA) (out *Image, copied bool) {
 	size := img.Bounds().Size()
 
 	out = New(size)
-	inPix, outVals := img.Pix, out.Vals
-	inLen := len(inPix)
-
-	for ip, op := 0, 0; ip < inLen; ip, op = ip+4, op+1 {
-		outVals[op] = color.RGBA{
-			R: inPix[ip+0],
-			G: inPix[ip+1],
-			B: inPix[ip+2],
-			A: inPix[ip+3],
+
+	for y := 0; y < size.Y; y++ {
+		inPix := img.Pix[y*img.Stride : y*img.Stride+size.X*4]
+		outVals := out.Vals[y*out.Stride : y*out.Stride+size.X]
+
+		for ip, op := 0, 0; ip < len(inPix); ip, op = ip+4, op+1 {
+			outVals[op] = color.RGBA{
+				R: inPix[ip+0],
+				G: inPix[ip+1],
+				B: inPix[ip+2],
+				A: inPix[ip+3],
+			}
 		}
 	}
 
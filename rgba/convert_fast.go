@@ -36,11 +36,23 @@ func convertRGBAToRGBA(img *image.RGBA) (out *Image, copied bool) {
 		return convertRGBAToRGBASlow(img)
 	}
 
-	vals, err := CastFromBytes(img.Pix)
+	size := img.Bounds().Size()
+	if img.Stride%4 != 0 {
+		return convertRGBAToRGBASlow(img)
+	}
+	stride := img.Stride / 4
+
+	// Sub-images share Pix with their parent, so it may continue past the end of
+	// the last row:
+	pix := img.Pix[:0]
+	if size.X > 0 && size.Y > 0 {
+		end := ((size.Y-1)*stride + size.X) * 4
+		pix = img.Pix[:end:end]
+	}
+
+	vals, err := CastFromBytes(pix)
 	if err != nil {
 		panic(err)
 	}
-
-	size := img.Bounds().Size()
-	return &Image{Size: size, Stride: size.X, Vals: vals}, false
+	return &Image{Size: size, Stride: stride, Vals: vals}, false
 }
@@ -1,8 +1,10 @@
 package rgba
 
 import (
+	"flag"
 	"fmt"
 	"image"
+	"image/color"
 	"math/rand"
 	"reflect"
 	"testing"
@@ -21,7 +23,7 @@ var (
 
 func TestCastBytes(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
-	gen := testimg.RandBlocks{512, 512, 32, 32}
+	gen := testimg.RandBlocks{W: 512, H: 512, BlockW: 32, BlockH: 32}
 	img, _ := Convert(gen.RGBA(rng))
 
 	bts, err := CastToBytes(img.Vals)
@@ -46,67 +48,116 @@ func TestCastBytes(t *testing.T) {
 
 func TestConvertRGBA(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
-	gen := testimg.RandBlocks{512, 512, 32, 32}
 
 	unwrap := func(v *Image, ok bool) *Image {
 		return v
 	}
 
-	var cases = []struct {
-		oimg image.Image
-		conv func(v image.Image) *Image
-	}{
-		{gen.RGBA(rng), func(v image.Image) *Image { return unwrap(convertRGBAToRGBA(v.(*image.RGBA))) }},
-		{gen.RGBA(rng), func(v image.Image) *Image { return unwrap(convertRGBAToRGBASlow(v.(*image.RGBA))) }},
-		{gen.RGBA(rng), func(v image.Image) *Image { return convertImageToRGBA(v.(*image.RGBA)) }},
-		{gen.RGBA(rng), func(v image.Image) *Image { return convertRGBAAtToRGBA(v.(*image.RGBA)) }},
-		{gen.RGBA64(rng), func(v image.Image) *Image { return convertRGBA64ToRGBA(v.(*image.RGBA64)) }},
-		{gen.NRGBA(rng), func(v image.Image) *Image { return convertNRGBAToRGBA(v.(*image.NRGBA)) }},
-		{gen.NRGBA64(rng), func(v image.Image) *Image { return convertNRGBA64ToRGBA(v.(*image.NRGBA64)) }},
-		{gen.YCbCr(rng), func(v image.Image) *Image { return convertYCbCrToRGBA(v.(*image.YCbCr)) }},
-		{gen.CMYK(rng), func(v image.Image) *Image { return convertCMYKToRGBA(v.(*image.CMYK)) }},
-		{gen.Paletted(rng, nil), func(v image.Image) *Image { return convertPalettedToRGBA(v.(*image.Paletted)) }},
+	for _, gen := range []testimg.Recipe{
+		testimg.RandBlocks{W: 512, H: 512, BlockW: 32, BlockH: 32},
+		testimg.SubImage{Base: testimg.RandBlocks{W: 512, H: 512, BlockW: 32, BlockH: 32}},
+	} {
+		var cases = []struct {
+			oimg image.Image
+			conv func(v image.Image) *Image
+		}{
+			{gen.RGBA(rng), func(v image.Image) *Image { return unwrap(convertRGBAToRGBA(v.(*image.RGBA))) }},
+			{gen.RGBA(rng), func(v image.Image) *Image { return unwrap(convertRGBAToRGBASlow(v.(*image.RGBA))) }},
+			{gen.RGBA(rng), func(v image.Image) *Image { return convertImageToRGBA(v.(*image.RGBA)) }},
+			{gen.RGBA(rng), func(v image.Image) *Image { return convertRGBAAtToRGBA(v.(*image.RGBA)) }},
+			{gen.RGBA64(rng), func(v image.Image) *Image { return convertRGBA64ToRGBA(v.(*image.RGBA64)) }},
+			{gen.NRGBA(rng), func(v image.Image) *Image { return convertNRGBAToRGBA(v.(*image.NRGBA)) }},
+			{gen.NRGBA64(rng), func(v image.Image) *Image { return convertNRGBA64ToRGBA(v.(*image.NRGBA64)) }},
+			{gen.YCbCr(rng), func(v image.Image) *Image { return convertYCbCrToRGBA(v.(*image.YCbCr)) }},
+			{gen.CMYK(rng), func(v image.Image) *Image { return convertCMYKToRGBA(v.(*image.CMYK)) }},
+			{gen.Paletted(rng, nil), func(v image.Image) *Image { return convertPalettedToRGBA(v.(*image.Paletted)) }},
+		}
+
+		for idx, tc := range cases {
+			t.Run(fmt.Sprintf("%T/%d/%T", gen, idx, tc.oimg), func(t *testing.T) {
+				testimg.AssertImage(t, tc.oimg, tc.conv(tc.oimg), 0)
+			})
+		}
 	}
+}
+
+func TestConvertYCbCr(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
 
-	for idx, tc := range cases {
-		t.Run(fmt.Sprintf("%d/%T", idx, tc.oimg), func(t *testing.T) {
-			oimg := tc.oimg
-			rimg := tc.conv(oimg)
-
-			bounds := rimg.Bounds()
-
-			for x := bounds.Min.X; x < bounds.Max.X; x++ {
-				for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-					var (
-						or, og, ob, _  = oimg.At(x, y).RGBA()
-						rr, rg, rb, ra = rimg.At(x, y).RGBA()
-					)
-
-					_ = ra
-
-					// Let's only test 8 bits; the YCbCr conversion gives test failures
-					// with the 16 bit values from RGBA, which is kinda unsurprising as we
-					// started with 8-bit values anyway.
-					if or>>8 != rr>>8 {
-						t.Fatalf("or(%d) != rr(%d) at (%d,%d)", or>>8, rr>>8, x, y)
-					}
-					if og>>8 != rg>>8 {
-						t.Fatalf("og(%d) != rg(%d) at (%d,%d)", og>>8, rg>>8, x, y)
-					}
-					if ob>>8 != rb>>8 {
-						t.Fatalf("ob(%d) != rb(%d) at (%d,%d)", ob>>8, rb>>8, x, y)
-					}
+	for _, ratio := range testimg.YCbCrSubsampleRatios {
+		// Odd sizes make sure the partial chroma samples at the edges are covered:
+		for _, sz := range []image.Point{{1, 1}, {37, 13}, {64, 64}} {
+			t.Run(fmt.Sprintf("%s/%dx%d", ratio, sz.X, sz.Y), func(t *testing.T) {
+				// Blocks line up with every chroma sample size, so the RGB values
+				// survive the trip through YCbCr:
+				gen := testimg.Subsampled{
+					Base:  testimg.RandBlocks{W: sz.X, H: sz.Y, BlockW: 4, BlockH: 2},
+					Ratio: ratio,
 				}
-			}
-		})
+				orig := gen.RGBA(rand.New(rand.NewSource(1)))
+				ycbcr := gen.YCbCr(rand.New(rand.NewSource(1)))
+				if ycbcr.SubsampleRatio != ratio {
+					t.Fatal(ycbcr.SubsampleRatio)
+				}
+
+				img := convertYCbCrToRGBA(ycbcr)
+				testimg.AssertImage(t, ycbcr, img, 0)
+				testimg.AssertImage(t, orig, img, 2)
+
+				smooth := testimg.ToYCbCr(testimg.Plasma{W: sz.X, H: sz.Y}.RGBA(rng), ratio)
+				img, _ = Convert(smooth)
+				testimg.AssertImage(t, smooth, img, 0)
+
+				// Sub-images at an origin that doesn't line up with the chroma samples:
+				sub := testimg.SubImage{Base: gen, Origin: image.Pt(3, 5), Pad: 2}.YCbCr(rng)
+				img, _ = Convert(sub)
+				testimg.AssertImage(t, sub, img, 0)
+			})
+		}
+	}
+}
+
+func TestConvertSmooth(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pal := testimg.RandPalette(rng, 256)
+
+	for _, rc := range smoothRecipes(67, 45) {
+		sub := testimg.SubImage{Base: rc.recipe}
+		for _, src := range []image.Image{
+			rc.recipe.RGBA(rng),
+			rc.recipe.RGBA64(rng),
+			rc.recipe.NRGBA(rng),
+			rc.recipe.NRGBA64(rng),
+			rc.recipe.YCbCr(rng),
+			rc.recipe.CMYK(rng),
+			rc.recipe.Paletted(rng, pal),
+			sub.RGBA(rng),
+			sub.RGBA64(rng),
+			sub.NRGBA(rng),
+			sub.NRGBA64(rng),
+			sub.YCbCr(rng),
+			sub.CMYK(rng),
+			sub.Paletted(rng, pal),
+		} {
+			t.Run(fmt.Sprintf("%s/%T/%v", rc.name, src, src.Bounds().Min), func(t *testing.T) {
+				img, _ := Convert(src)
+				testimg.AssertImage(t, src, img, 0)
+			})
+		}
 	}
 }
 
 var BenchmarkImage image.Image
 
+var benchRecipe = flag.String("recipe", "randblocks:512x512:32x32",
+	"testimg recipe spec for the images used by BenchmarkConvert")
+
 func BenchmarkConvert(b *testing.B) {
 	rng := rand.New(rand.NewSource(0))
-	gen := testimg.RandBlocks{512, 512, 32, 32}
+	gen, err := testimg.ParseRecipe(*benchRecipe)
+	if err != nil {
+		b.Fatal(err)
+	}
 
 	b.Run("rgba", func(b *testing.B) {
 		in := gen.RGBA(rng)
@@ -206,3 +257,47 @@ func BenchmarkArrayAccess(b *testing.B) {
 		}
 	})
 }
+
+func TestImageSetNRGBAMatchesConvert(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(255 - x), uint8(x / 2), uint8(y)})
+		}
+	}
+	conv, _ := Convert(src)
+	set := New(conv.Size)
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			set.Set(x, y, src.NRGBAAt(x, y))
+		}
+	}
+	testimg.AssertImage(t, conv, set, 0)
+}
+
+func TestPremultiplyNRGBAMatchesStdlib(t *testing.T) {
+	// Every NRGBA conversion in the package must agree with color.RGBAModel:
+	for a := 0; a < 256; a++ {
+		for v := 0; v < 256; v++ {
+			nc := color.NRGBA{uint8(v), uint8(255 - v), uint8(v / 3), uint8(a)}
+			want := color.RGBAModel.Convert(nc).(color.RGBA)
+
+			hex := fmt.Sprintf("#%02x%02x%02x%02x", nc.R, nc.G, nc.B, nc.A)
+			if c, err := FromNRGBAHex(hex); err != nil || c != want {
+				t.Fatalf("FromNRGBAHex(%q): expected %v, found %v (%v)", hex, want, c, err)
+			}
+			if c, err := FromCSS(hex); err != nil || c != want {
+				t.Fatalf("FromCSS(%q): expected %v, found %v (%v)", hex, want, c, err)
+			}
+			if c := ConvertPalette(color.Palette{nc})[0]; c != want {
+				t.Fatalf("ConvertPalette(%v): expected %v, found %v", nc, want, c)
+			}
+			if c := NormalizePalette(color.Palette{nc})[0]; c != want {
+				t.Fatalf("NormalizePalette(%v): expected %v, found %v", nc, want, c)
+			}
+			if c := colorToRGBA(nc); c != want {
+				t.Fatalf("colorToRGBA(%v): expected %v, found %v", nc, want, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,529 @@
+package rgba
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NRGBAFromCSS parses a CSS colour. It accepts:
+//
+//	#rgb, #rgba, #rrggbb, #rrggbbaa
+//	rgb(), rgba()      numbers from 0 to 255, or percentages
+//	hsl(), hsla()      hue in deg (the default), rad, grad or turn
+//	hwb()
+//	named colours      every CSS named colour, and "transparent"
+//
+// The functions accept both the legacy comma-separated syntax, "rgba(1, 2, 3, 0.5)",
+// and the space-separated syntax with an optional alpha after a slash,
+// "rgb(1 2 3 / 50%)". Any component may be "none", which is treated as zero.
+// Components outside their range are clamped, as CSS does.
+//
+// Parsing is case-insensitive and ignores surrounding whitespace. "currentcolor"
+// and the colour spaces of CSS Color 4's color() function are not supported.
+//
+func NRGBAFromCSS(s string) (c color.NRGBA, err error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return c, fmt.Errorf("rgba: empty CSS colour")
+	}
+
+	if s[0] == '#' {
+		return nrgbaFromCSSHex(s)
+	}
+	if c, ok := cssNamedColors[s]; ok {
+		return c, nil
+	}
+
+	open := strings.IndexByte(s, '(')
+	if open < 0 || s[len(s)-1] != ')' {
+		return c, fmt.Errorf("rgba: invalid CSS colour %q", s)
+	}
+	fn := strings.TrimSpace(s[:open])
+	args, err := cssArgs(s[open+1 : len(s)-1])
+	if err != nil {
+		return c, fmt.Errorf("rgba: invalid CSS colour %q: %v", s, err)
+	}
+
+	switch fn {
+	case "rgb", "rgba":
+		c, err = cssRGB(args)
+	case "hsl", "hsla":
+		c, err = cssHSL(args)
+	case "hwb":
+		if args.commas {
+			err = fmt.Errorf("hwb() does not allow commas")
+		} else {
+			c, err = cssHWB(args)
+		}
+	default:
+		err = fmt.Errorf("unknown function %q", fn)
+	}
+	if err != nil {
+		return c, fmt.Errorf("rgba: invalid CSS colour %q: %v", s, err)
+	}
+	return c, nil
+}
+
+// FromCSS parses a CSS colour as NRGBAFromCSS does, and returns it premultiplied.
+func FromCSS(s string) (c color.RGBA, err error) {
+	nc, err := NRGBAFromCSS(s)
+	if err != nil {
+		return c, err
+	}
+	return color.RGBAModel.Convert(nc).(color.RGBA), nil
+}
+
+// NRGBAToCSS formats c as the shortest CSS colour that NRGBAFromCSS parses back to
+// exactly c. That is a named colour if there is a short enough one, otherwise the
+// shortest of #RGB, #RGBA, #RRGGBB and #RRGGBBAA. Where a name and a hex colour are
+// the same length, the hex colour is used.
+func NRGBAToCSS(c color.NRGBA) string {
+	var buf [9]byte
+	var n int
+	buf[0] = '#'
+
+	if c.R>>4 == c.R&0xf && c.G>>4 == c.G&0xf && c.B>>4 == c.B&0xf && c.A>>4 == c.A&0xf {
+		buf[1], buf[2] = hexStrs[int(c.R)*2], hexStrs[int(c.G)*2]
+		buf[3], buf[4] = hexStrs[int(c.B)*2], hexStrs[int(c.A)*2]
+		n = 4
+		if c.A != 0xff {
+			n = 5
+		}
+	} else {
+		copy(buf[1:3], hexStrs[int(c.R)*2:])
+		copy(buf[3:5], hexStrs[int(c.G)*2:])
+		copy(buf[5:7], hexStrs[int(c.B)*2:])
+		copy(buf[7:9], hexStrs[int(c.A)*2:])
+		n = 7
+		if c.A != 0xff {
+			n = 9
+		}
+	}
+
+	if name, ok := cssColorNames[c]; ok && len(name) < n {
+		return name
+	}
+	return string(buf[:n])
+}
+
+// ToCSS formats the premultiplied colour c as NRGBAToCSS does.
+func ToCSS(c color.RGBA) string {
+	return NRGBAToCSS(color.NRGBAModel.Convert(c).(color.NRGBA))
+}
+
+func nrgbaFromCSSHex(s string) (c color.NRGBA, err error) {
+	digits := s[1:]
+	switch len(digits) {
+	case 3, 4:
+		v := [4]int{3: 0xf}
+		for i := 0; i < len(digits); i++ {
+			v[i] = hexVals[digits[i]]
+			if v[i] < 0 {
+				return c, fmt.Errorf("rgba: invalid CSS colour %q", s)
+			}
+		}
+		return color.NRGBA{R: uint8(v[0] * 0x11), G: uint8(v[1] * 0x11), B: uint8(v[2] * 0x11), A: uint8(v[3] * 0x11)}, nil
+
+	case 6, 8:
+		c, err = NRGBAFromNRGBAHex(digits)
+		if err != nil {
+			return c, fmt.Errorf("rgba: invalid CSS colour %q", s)
+		}
+		return c, nil
+	}
+	return c, fmt.Errorf("rgba: invalid CSS colour %q", s)
+}
+
+// cssArguments holds the components of a CSS colour function, with the alpha
+// (if any) split out.
+type cssArguments struct {
+	vals   []string
+	alpha  string
+	commas bool
+}
+
+func cssArgs(s string) (args cssArguments, err error) {
+	if strings.ContainsRune(s, ',') {
+		args.commas = true
+		if strings.ContainsRune(s, '/') {
+			return args, fmt.Errorf("can not mix commas and slashes")
+		}
+		for _, v := range strings.Split(s, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" || strings.ContainsAny(v, " \t\n") {
+				return args, fmt.Errorf("invalid arguments")
+			}
+			args.vals = append(args.vals, v)
+		}
+		if len(args.vals) == 4 {
+			args.alpha = args.vals[3]
+			args.vals = args.vals[:3]
+		}
+
+	} else {
+		parts := strings.Split(s, "/")
+		if len(parts) > 2 {
+			return args, fmt.Errorf("too many slashes")
+		}
+		args.vals = strings.Fields(parts[0])
+		if len(parts) == 2 {
+			alpha := strings.Fields(parts[1])
+			if len(alpha) != 1 {
+				return args, fmt.Errorf("invalid alpha")
+			}
+			args.alpha = alpha[0]
+		}
+	}
+
+	if len(args.vals) != 3 {
+		return args, fmt.Errorf("expected 3 components")
+	}
+	return args, nil
+}
+
+// cssNumber parses a CSS number, followed by an optional unit such as "%" or
+// "deg". Only plain decimal numbers are accepted, not the hex floats, infinities
+// or underscores that strconv.ParseFloat would allow. "none" is zero.
+func cssNumber(s string) (v float64, unit string, err error) {
+	if s == "none" {
+		return 0, "", nil
+	}
+	end := 0
+	for end < len(s) && strings.IndexByte("0123456789.+-e", s[end]) >= 0 {
+		// Don't take the "e" at the start of a unit as an exponent:
+		if s[end] == 'e' && (end+1 >= len(s) || strings.IndexByte("0123456789+-", s[end+1]) < 0) {
+			break
+		}
+		end++
+	}
+	if end == 0 {
+		return 0, "", fmt.Errorf("invalid number %q", s)
+	}
+	v, err = strconv.ParseFloat(s[:end], 64)
+	if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, "", fmt.Errorf("invalid number %q", s)
+	}
+	return v, s[end:], nil
+}
+
+// cssAlpha parses an alpha component, which is a number from 0 to 1 or a
+// percentage. A missing alpha is opaque.
+func cssAlpha(s string) (uint8, error) {
+	if s == "" {
+		return 0xff, nil
+	}
+	v, unit, err := cssNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	switch unit {
+	case "":
+	case "%":
+		v /= 100
+	default:
+		return 0, fmt.Errorf("invalid alpha %q", s)
+	}
+	return cssUnit8(v), nil
+}
+
+// cssPercent parses a percentage, or a plain number, which CSS Color 4 treats the
+// same way, into the range [0, 1].
+func cssPercent(s string) (float64, error) {
+	v, unit, err := cssNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	if unit != "" && unit != "%" {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return math.Max(0, math.Min(1, v/100)), nil
+}
+
+// cssHue parses a hue into degrees in the range [0, 360).
+func cssHue(s string) (float64, error) {
+	v, unit, err := cssNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	switch unit {
+	case "", "deg":
+	case "rad":
+		v = v * 180 / math.Pi
+	case "grad":
+		v = v * 360 / 400
+	case "turn":
+		v = v * 360
+	default:
+		return 0, fmt.Errorf("invalid hue %q", s)
+	}
+	v = math.Mod(v, 360)
+	if v < 0 {
+		v += 360
+	}
+	return v, nil
+}
+
+// cssUnit8 scales v from [0, 1] to [0, 255], clamping and rounding.
+func cssUnit8(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+func cssRGB(args cssArguments) (c color.NRGBA, err error) {
+	var ch [3]uint8
+	for i, s := range args.vals {
+		v, unit, err := cssNumber(s)
+		if err != nil {
+			return c, err
+		}
+		switch unit {
+		case "":
+			ch[i] = cssUnit8(v / 255)
+		case "%":
+			ch[i] = cssUnit8(v / 100)
+		default:
+			return c, fmt.Errorf("invalid component %q", s)
+		}
+	}
+	a, err := cssAlpha(args.alpha)
+	if err != nil {
+		return c, err
+	}
+	return color.NRGBA{R: ch[0], G: ch[1], B: ch[2], A: a}, nil
+}
+
+func cssHSL(args cssArguments) (c color.NRGBA, err error) {
+	h, err := cssHue(args.vals[0])
+	if err != nil {
+		return c, err
+	}
+	s, err := cssPercent(args.vals[1])
+	if err != nil {
+		return c, err
+	}
+	l, err := cssPercent(args.vals[2])
+	if err != nil {
+		return c, err
+	}
+	a, err := cssAlpha(args.alpha)
+	if err != nil {
+		return c, err
+	}
+	r, g, b := hslToRGB(h, s, l)
+	return color.NRGBA{R: cssUnit8(r), G: cssUnit8(g), B: cssUnit8(b), A: a}, nil
+}
+
+func cssHWB(args cssArguments) (c color.NRGBA, err error) {
+	h, err := cssHue(args.vals[0])
+	if err != nil {
+		return c, err
+	}
+	w, err := cssPercent(args.vals[1])
+	if err != nil {
+		return c, err
+	}
+	bl, err := cssPercent(args.vals[2])
+	if err != nil {
+		return c, err
+	}
+	a, err := cssAlpha(args.alpha)
+	if err != nil {
+		return c, err
+	}
+
+	var r, g, b float64
+	if w+bl >= 1 {
+		r = w / (w + bl)
+		g, b = r, r
+	} else {
+		r, g, b = hslToRGB(h, 1, 0.5)
+		r = r*(1-w-bl) + w
+		g = g*(1-w-bl) + w
+		b = b*(1-w-bl) + w
+	}
+	return color.NRGBA{R: cssUnit8(r), G: cssUnit8(g), B: cssUnit8(b), A: a}, nil
+}
+
+// hslToRGB converts a hue in degrees, and a saturation and lightness in [0, 1], to
+// gamma-encoded RGB in [0, 1], using the algorithm from CSS Color 4.
+func hslToRGB(h, s, l float64) (r, g, b float64) {
+	f := func(n float64) float64 {
+		k := math.Mod(n+h/30, 12)
+		a := s * math.Min(l, 1-l)
+		return l - a*math.Max(-1, math.Min(math.Min(k-3, 9-k), 1))
+	}
+	return f(0), f(8), f(4)
+}
+
+// cssColorNames maps each named colour to its shortest name, for NRGBAToCSS. Ties
+// go to the first name alphabetically, so "aqua" is used rather than "cyan".
+var cssColorNames = map[color.NRGBA]string{}
+
+func init() {
+	names := make([]string, 0, len(cssNamedColors))
+	for name := range cssNamedColors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := cssNamedColors[name]
+		if cur, ok := cssColorNames[c]; !ok || len(name) < len(cur) {
+			cssColorNames[c] = name
+		}
+	}
+}
+
+var cssNamedColors = map[string]color.NRGBA{
+	"aliceblue":            {0xf0, 0xf8, 0xff, 0xff},
+	"antiquewhite":         {0xfa, 0xeb, 0xd7, 0xff},
+	"aqua":                 {0x00, 0xff, 0xff, 0xff},
+	"aquamarine":           {0x7f, 0xff, 0xd4, 0xff},
+	"azure":                {0xf0, 0xff, 0xff, 0xff},
+	"beige":                {0xf5, 0xf5, 0xdc, 0xff},
+	"bisque":               {0xff, 0xe4, 0xc4, 0xff},
+	"black":                {0x00, 0x00, 0x00, 0xff},
+	"blanchedalmond":       {0xff, 0xeb, 0xcd, 0xff},
+	"blue":                 {0x00, 0x00, 0xff, 0xff},
+	"blueviolet":           {0x8a, 0x2b, 0xe2, 0xff},
+	"brown":                {0xa5, 0x2a, 0x2a, 0xff},
+	"burlywood":            {0xde, 0xb8, 0x87, 0xff},
+	"cadetblue":            {0x5f, 0x9e, 0xa0, 0xff},
+	"chartreuse":           {0x7f, 0xff, 0x00, 0xff},
+	"chocolate":            {0xd2, 0x69, 0x1e, 0xff},
+	"coral":                {0xff, 0x7f, 0x50, 0xff},
+	"cornflowerblue":       {0x64, 0x95, 0xed, 0xff},
+	"cornsilk":             {0xff, 0xf8, 0xdc, 0xff},
+	"crimson":              {0xdc, 0x14, 0x3c, 0xff},
+	"cyan":                 {0x00, 0xff, 0xff, 0xff},
+	"darkblue":             {0x00, 0x00, 0x8b, 0xff},
+	"darkcyan":             {0x00, 0x8b, 0x8b, 0xff},
+	"darkgoldenrod":        {0xb8, 0x86, 0x0b, 0xff},
+	"darkgray":             {0xa9, 0xa9, 0xa9, 0xff},
+	"darkgreen":            {0x00, 0x64, 0x00, 0xff},
+	"darkgrey":             {0xa9, 0xa9, 0xa9, 0xff},
+	"darkkhaki":            {0xbd, 0xb7, 0x6b, 0xff},
+	"darkmagenta":          {0x8b, 0x00, 0x8b, 0xff},
+	"darkolivegreen":       {0x55, 0x6b, 0x2f, 0xff},
+	"darkorange":           {0xff, 0x8c, 0x00, 0xff},
+	"darkorchid":           {0x99, 0x32, 0xcc, 0xff},
+	"darkred":              {0x8b, 0x00, 0x00, 0xff},
+	"darksalmon":           {0xe9, 0x96, 0x7a, 0xff},
+	"darkseagreen":         {0x8f, 0xbc, 0x8f, 0xff},
+	"darkslateblue":        {0x48, 0x3d, 0x8b, 0xff},
+	"darkslategray":        {0x2f, 0x4f, 0x4f, 0xff},
+	"darkslategrey":        {0x2f, 0x4f, 0x4f, 0xff},
+	"darkturquoise":        {0x00, 0xce, 0xd1, 0xff},
+	"darkviolet":           {0x94, 0x00, 0xd3, 0xff},
+	"deeppink":             {0xff, 0x14, 0x93, 0xff},
+	"deepskyblue":          {0x00, 0xbf, 0xff, 0xff},
+	"dimgray":              {0x69, 0x69, 0x69, 0xff},
+	"dimgrey":              {0x69, 0x69, 0x69, 0xff},
+	"dodgerblue":           {0x1e, 0x90, 0xff, 0xff},
+	"firebrick":            {0xb2, 0x22, 0x22, 0xff},
+	"floralwhite":          {0xff, 0xfa, 0xf0, 0xff},
+	"forestgreen":          {0x22, 0x8b, 0x22, 0xff},
+	"fuchsia":              {0xff, 0x00, 0xff, 0xff},
+	"gainsboro":            {0xdc, 0xdc, 0xdc, 0xff},
+	"ghostwhite":           {0xf8, 0xf8, 0xff, 0xff},
+	"gold":                 {0xff, 0xd7, 0x00, 0xff},
+	"goldenrod":            {0xda, 0xa5, 0x20, 0xff},
+	"gray":                 {0x80, 0x80, 0x80, 0xff},
+	"green":                {0x00, 0x80, 0x00, 0xff},
+	"greenyellow":          {0xad, 0xff, 0x2f, 0xff},
+	"grey":                 {0x80, 0x80, 0x80, 0xff},
+	"honeydew":             {0xf0, 0xff, 0xf0, 0xff},
+	"hotpink":              {0xff, 0x69, 0xb4, 0xff},
+	"indianred":            {0xcd, 0x5c, 0x5c, 0xff},
+	"indigo":               {0x4b, 0x00, 0x82, 0xff},
+	"ivory":                {0xff, 0xff, 0xf0, 0xff},
+	"khaki":                {0xf0, 0xe6, 0x8c, 0xff},
+	"lavender":             {0xe6, 0xe6, 0xfa, 0xff},
+	"lavenderblush":        {0xff, 0xf0, 0xf5, 0xff},
+	"lawngreen":            {0x7c, 0xfc, 0x00, 0xff},
+	"lemonchiffon":         {0xff, 0xfa, 0xcd, 0xff},
+	"lightblue":            {0xad, 0xd8, 0xe6, 0xff},
+	"lightcoral":           {0xf0, 0x80, 0x80, 0xff},
+	"lightcyan":            {0xe0, 0xff, 0xff, 0xff},
+	"lightgoldenrodyellow": {0xfa, 0xfa, 0xd2, 0xff},
+	"lightgray":            {0xd3, 0xd3, 0xd3, 0xff},
+	"lightgreen":           {0x90, 0xee, 0x90, 0xff},
+	"lightgrey":            {0xd3, 0xd3, 0xd3, 0xff},
+	"lightpink":            {0xff, 0xb6, 0xc1, 0xff},
+	"lightsalmon":          {0xff, 0xa0, 0x7a, 0xff},
+	"lightseagreen":        {0x20, 0xb2, 0xaa, 0xff},
+	"lightskyblue":         {0x87, 0xce, 0xfa, 0xff},
+	"lightslategray":       {0x77, 0x88, 0x99, 0xff},
+	"lightslategrey":       {0x77, 0x88, 0x99, 0xff},
+	"lightsteelblue":       {0xb0, 0xc4, 0xde, 0xff},
+	"lightyellow":          {0xff, 0xff, 0xe0, 0xff},
+	"lime":                 {0x00, 0xff, 0x00, 0xff},
+	"limegreen":            {0x32, 0xcd, 0x32, 0xff},
+	"linen":                {0xfa, 0xf0, 0xe6, 0xff},
+	"magenta":              {0xff, 0x00, 0xff, 0xff},
+	"maroon":               {0x80, 0x00, 0x00, 0xff},
+	"mediumaquamarine":     {0x66, 0xcd, 0xaa, 0xff},
+	"mediumblue":           {0x00, 0x00, 0xcd, 0xff},
+	"mediumorchid":         {0xba, 0x55, 0xd3, 0xff},
+	"mediumpurple":         {0x93, 0x70, 0xdb, 0xff},
+	"mediumseagreen":       {0x3c, 0xb3, 0x71, 0xff},
+	"mediumslateblue":      {0x7b, 0x68, 0xee, 0xff},
+	"mediumspringgreen":    {0x00, 0xfa, 0x9a, 0xff},
+	"mediumturquoise":      {0x48, 0xd1, 0xcc, 0xff},
+	"mediumvioletred":      {0xc7, 0x15, 0x85, 0xff},
+	"midnightblue":         {0x19, 0x19, 0x70, 0xff},
+	"mintcream":            {0xf5, 0xff, 0xfa, 0xff},
+	"mistyrose":            {0xff, 0xe4, 0xe1, 0xff},
+	"moccasin":             {0xff, 0xe4, 0xb5, 0xff},
+	"navajowhite":          {0xff, 0xde, 0xad, 0xff},
+	"navy":                 {0x00, 0x00, 0x80, 0xff},
+	"oldlace":              {0xfd, 0xf5, 0xe6, 0xff},
+	"olive":                {0x80, 0x80, 0x00, 0xff},
+	"olivedrab":            {0x6b, 0x8e, 0x23, 0xff},
+	"orange":               {0xff, 0xa5, 0x00, 0xff},
+	"orangered":            {0xff, 0x45, 0x00, 0xff},
+	"orchid":               {0xda, 0x70, 0xd6, 0xff},
+	"palegoldenrod":        {0xee, 0xe8, 0xaa, 0xff},
+	"palegreen":            {0x98, 0xfb, 0x98, 0xff},
+	"paleturquoise":        {0xaf, 0xee, 0xee, 0xff},
+	"palevioletred":        {0xdb, 0x70, 0x93, 0xff},
+	"papayawhip":           {0xff, 0xef, 0xd5, 0xff},
+	"peachpuff":            {0xff, 0xda, 0xb9, 0xff},
+	"peru":                 {0xcd, 0x85, 0x3f, 0xff},
+	"pink":                 {0xff, 0xc0, 0xcb, 0xff},
+	"plum":                 {0xdd, 0xa0, 0xdd, 0xff},
+	"powderblue":           {0xb0, 0xe0, 0xe6, 0xff},
+	"purple":               {0x80, 0x00, 0x80, 0xff},
+	"rebeccapurple":        {0x66, 0x33, 0x99, 0xff},
+	"red":                  {0xff, 0x00, 0x00, 0xff},
+	"rosybrown":            {0xbc, 0x8f, 0x8f, 0xff},
+	"royalblue":            {0x41, 0x69, 0xe1, 0xff},
+	"saddlebrown":          {0x8b, 0x45, 0x13, 0xff},
+	"salmon":               {0xfa, 0x80, 0x72, 0xff},
+	"sandybrown":           {0xf4, 0xa4, 0x60, 0xff},
+	"seagreen":             {0x2e, 0x8b, 0x57, 0xff},
+	"seashell":             {0xff, 0xf5, 0xee, 0xff},
+	"sienna":               {0xa0, 0x52, 0x2d, 0xff},
+	"silver":               {0xc0, 0xc0, 0xc0, 0xff},
+	"skyblue":              {0x87, 0xce, 0xeb, 0xff},
+	"slateblue":            {0x6a, 0x5a, 0xcd, 0xff},
+	"slategray":            {0x70, 0x80, 0x90, 0xff},
+	"slategrey":            {0x70, 0x80, 0x90, 0xff},
+	"snow":                 {0xff, 0xfa, 0xfa, 0xff},
+	"springgreen":          {0x00, 0xff, 0x7f, 0xff},
+	"steelblue":            {0x46, 0x82, 0xb4, 0xff},
+	"tan":                  {0xd2, 0xb4, 0x8c, 0xff},
+	"teal":                 {0x00, 0x80, 0x80, 0xff},
+	"thistle":              {0xd8, 0xbf, 0xd8, 0xff},
+	"tomato":               {0xff, 0x63, 0x47, 0xff},
+	"transparent":          {0x00, 0x00, 0x00, 0x00},
+	"turquoise":            {0x40, 0xe0, 0xd0, 0xff},
+	"violet":               {0xee, 0x82, 0xee, 0xff},
+	"wheat":                {0xf5, 0xde, 0xb3, 0xff},
+	"white":                {0xff, 0xff, 0xff, 0xff},
+	"whitesmoke":           {0xf5, 0xf5, 0xf5, 0xff},
+	"yellow":               {0xff, 0xff, 0x00, 0xff},
+	"yellowgreen":          {0x9a, 0xcd, 0x32, 0xff},
+}
@@ -0,0 +1,130 @@
+package rgba
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func TestNRGBAFromCSS(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		out color.NRGBA
+	}{
+		{"#f00", color.NRGBA{0xff, 0, 0, 0xff}},
+		{"#F00", color.NRGBA{0xff, 0, 0, 0xff}},
+		{"#f008", color.NRGBA{0xff, 0, 0, 0x88}},
+		{"#123456", color.NRGBA{0x12, 0x34, 0x56, 0xff}},
+		{"#12345678", color.NRGBA{0x12, 0x34, 0x56, 0x78}},
+		{"  red  ", color.NRGBA{0xff, 0, 0, 0xff}},
+		{"RebeccaPurple", color.NRGBA{0x66, 0x33, 0x99, 0xff}},
+		{"grey", color.NRGBA{0x80, 0x80, 0x80, 0xff}},
+		{"transparent", color.NRGBA{}},
+		{"rgb(1, 2, 3)", color.NRGBA{1, 2, 3, 0xff}},
+		{"rgba(1, 2, 3, 0.5)", color.NRGBA{1, 2, 3, 0x80}},
+		{"rgba(1,2,3,50%)", color.NRGBA{1, 2, 3, 0x80}},
+		{"rgb(1 2 3)", color.NRGBA{1, 2, 3, 0xff}},
+		{"rgb(1 2 3 / 0)", color.NRGBA{1, 2, 3, 0}},
+		{"rgb(100% 50% 0%)", color.NRGBA{0xff, 0x80, 0, 0xff}},
+		{"rgb(300 -5 none)", color.NRGBA{0xff, 0, 0, 0xff}},
+		{"rgb(1.4 1.5 2.5e1)", color.NRGBA{1, 2, 25, 0xff}},
+		{"rgba(1 2 3 / 2)", color.NRGBA{1, 2, 3, 0xff}},
+		{"hsl(0, 100%, 50%)", color.NRGBA{0xff, 0, 0, 0xff}},
+		{"hsl(120deg 100% 25%)", color.NRGBA{0, 0x80, 0, 0xff}},
+		{"hsl(-120 100% 50%)", color.NRGBA{0, 0, 0xff, 0xff}},
+		{"hsl(0.5turn 100% 50%)", color.NRGBA{0, 0xff, 0xff, 0xff}},
+		{"hsl(200grad 100% 50%)", color.NRGBA{0, 0xff, 0xff, 0xff}},
+		{"hsl(3.14159265rad 100% 50%)", color.NRGBA{0, 0xff, 0xff, 0xff}},
+		{"hsla(0, 0%, 100%, 0.25)", color.NRGBA{0xff, 0xff, 0xff, 0x40}},
+		{"hsl(270 60% 40%)", color.NRGBA{0x66, 0x29, 0xa3, 0xff}},
+		{"hwb(0 0% 0%)", color.NRGBA{0xff, 0, 0, 0xff}},
+		{"hwb(120 20% 30%)", color.NRGBA{0x33, 0xb3, 0x33, 0xff}},
+		{"hwb(0 60% 60% / 50%)", color.NRGBA{0x80, 0x80, 0x80, 0x80}},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			c, err := NRGBAFromCSS(tc.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c != tc.out {
+				t.Fatalf("expected %v, found %v", tc.out, c)
+			}
+		})
+	}
+}
+
+func TestNRGBAFromCSSInvalid(t *testing.T) {
+	for _, in := range []string{
+		"", " ", "#", "#12", "#12345", "#1234567", "#123456789", "#ggg", "#12345g",
+		"nope", "currentcolor", "rgb", "rgb(", "rgb()", "rgb(1 2)", "rgb(1 2 3 4)",
+		"rgb(1, 2, 3 / 4)", "rgb(1, 2 3)", "rgb(1,,2,3)", "rgb(1 2 3 / 4 / 5)", "rgb(1 2 3 /)",
+		"rgb(1deg 2 3)", "rgb(inf 2 3)", "rgb(nan 2 3)", "rgb(0x1p1 2 3)", "rgb(1_0 2 3)", "rgb(1e 2 3)",
+		"hsl(1px 2% 3%)", "hsl(1 2deg 3%)", "hwb(1, 2%, 3%)", "rgb(1 2 3 / 1deg)", "rgb(1 2 3)x",
+		"hsv(1 2 3)", "rgb(1 2 3))", "rgb((1 2 3)",
+	} {
+		if _, err := NRGBAFromCSS(in); err == nil {
+			t.Fatalf("expected error for %q", in)
+		}
+		if _, err := FromCSS(in); err == nil {
+			t.Fatalf("expected error for %q", in)
+		}
+	}
+}
+
+func TestNRGBAToCSS(t *testing.T) {
+	for _, tc := range []struct {
+		in  color.NRGBA
+		out string
+	}{
+		{color.NRGBA{0xff, 0, 0, 0xff}, "red"},
+		{color.NRGBA{0xff, 0xff, 0xff, 0xff}, "#FFF"},
+		{color.NRGBA{0, 0xff, 0xff, 0xff}, "#0FF"},
+		{color.NRGBA{0xd2, 0xb4, 0x8c, 0xff}, "tan"},
+		{color.NRGBA{0, 0, 0x80, 0xff}, "navy"},
+		{color.NRGBA{0x80, 0x80, 0x80, 0xff}, "gray"},
+		{color.NRGBA{0xff, 0xa5, 0, 0xff}, "orange"},
+		{color.NRGBA{0xf0, 0xf8, 0xff, 0xff}, "#F0F8FF"},
+		{color.NRGBA{0x11, 0x22, 0x33, 0x44}, "#1234"},
+		{color.NRGBA{0x12, 0x34, 0x56, 0xff}, "#123456"},
+		{color.NRGBA{0x12, 0x34, 0x56, 0x78}, "#12345678"},
+		{color.NRGBA{}, "#0000"},
+	} {
+		if css := NRGBAToCSS(tc.in); css != tc.out {
+			t.Fatalf("%v: expected %q, found %q", tc.in, tc.out, css)
+		}
+	}
+
+	if css := ToCSS(color.RGBA{0x80, 0, 0, 0x80}); css != "#FF000080" {
+		t.Fatal(css)
+	}
+}
+
+func TestCSSNamedRoundTrip(t *testing.T) {
+	for name, c := range cssNamedColors {
+		css := NRGBAToCSS(c)
+		back, err := NRGBAFromCSS(css)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if back != c {
+			t.Fatalf("%s: expected %v, found %v via %q", name, c, back, css)
+		}
+	}
+}
+
+func TestCSSRand(t *testing.T) {
+	rng := testimg.NewRNG(0)
+	for i := 0; i < 10000; i++ {
+		col := testimg.ColorDist{}.RGBA(rng)
+		nc := color.NRGBAModel.Convert(col).(color.NRGBA)
+		css := NRGBAToCSS(nc)
+		back, err := NRGBAFromCSS(css)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back != nc {
+			t.Fatalf("expected %v, found %v via %q", nc, back, css)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package rgba
+
+import (
+	"image/color"
+	"math"
+	"sort"
+)
+
+// DominantColor is a colour found by DominantColors, along with the share of the
+// image's (non-transparent) pixels that it represents, between 0 and 1.
+type DominantColor struct {
+	Color color.RGBA
+	Share float64
+}
+
+// DominantOptions controls DominantColors. The zero value is usable.
+type DominantOptions struct {
+	// Bits of precision per channel used for the ColorHistogram. Defaults to 4.
+	Bits uint
+
+	// Perceptual merges similar colours by CIEDE2000 difference rather than by
+	// Euclidean distance in RGB.
+	Perceptual bool
+
+	// MergeDistance is the distance below which two colours are merged into one.
+	// This is a ΔE if Perceptual is true, otherwise it is a Euclidean RGB distance.
+	// Defaults to 10 if Perceptual, or 32 if not. Use a negative value to disable
+	// merging.
+	MergeDistance float64
+}
+
+// DominantColors finds the n most common colours in img, ordered by their share of
+// the image. Fewer than n may be returned if the image does not contain enough
+// distinct colours.
+//
+// Colours are first counted in a ColorHistogram; the bins are then merged greedily,
+// most populous first, into any already-found colour within MergeDistance. Each
+// result is the population-weighted mean of the bins merged into it.
+//
+// Fully transparent pixels are ignored.
+//
+func DominantColors(img *Image, n int, opts *DominantOptions) []DominantColor {
+	var o DominantOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Bits == 0 {
+		o.Bits = 4
+	}
+	if o.MergeDistance == 0 {
+		if o.Perceptual {
+			o.MergeDistance = 10
+		} else {
+			o.MergeDistance = 32
+		}
+	}
+
+	h := NewColorHistogram(img, o.Bits)
+	return dominantFromHistogram(h, n, o)
+}
+
+type dominantCluster struct {
+	count    int
+	r, g, b  float64 // population-weighted sums
+	l, a, bb float64 // CIELAB of the cluster's first (most populous) bin
+	seed     color.RGBA
+}
+
+func dominantFromHistogram(h *ColorHistogram, n int, o DominantOptions) []DominantColor {
+	if n <= 0 || h.Total == 0 {
+		return nil
+	}
+
+	bins := make([]int, 0, 256)
+	for bin, cnt := range h.Counts {
+		if cnt > 0 {
+			bins = append(bins, bin)
+		}
+	}
+	sort.SliceStable(bins, func(i, j int) bool {
+		return h.Counts[bins[i]] > h.Counts[bins[j]]
+	})
+
+	var clusters []*dominantCluster
+	for _, bin := range bins {
+		mean := h.Mean(bin)
+		cnt := h.Counts[bin]
+
+		var l, a, b float64
+		if o.Perceptual {
+			l, a, b = labFromRGBA(mean)
+		}
+
+		var into *dominantCluster
+		if o.MergeDistance > 0 {
+			best := o.MergeDistance
+			for _, cl := range clusters {
+				var d float64
+				if o.Perceptual {
+					d = deltaE2000(cl.l, cl.a, cl.bb, l, a, b)
+				} else {
+					d = math.Sqrt(float64(MetricRGB(cl.seed, mean)))
+				}
+				if d < best {
+					into, best = cl, d
+				}
+			}
+		}
+
+		if into == nil {
+			into = &dominantCluster{seed: mean, l: l, a: a, bb: b}
+			clusters = append(clusters, into)
+		}
+		into.count += cnt
+		into.r += float64(mean.R) * float64(cnt)
+		into.g += float64(mean.G) * float64(cnt)
+		into.b += float64(mean.B) * float64(cnt)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].count > clusters[j].count
+	})
+	if len(clusters) > n {
+		clusters = clusters[:n]
+	}
+
+	out := make([]DominantColor, len(clusters))
+	for i, cl := range clusters {
+		cnt := float64(cl.count)
+		out[i] = DominantColor{
+			Color: color.RGBA{
+				R: uint8(math.Round(cl.r / cnt)),
+				G: uint8(math.Round(cl.g / cnt)),
+				B: uint8(math.Round(cl.b / cnt)),
+				A: 0xff,
+			},
+			Share: cnt / float64(h.Total),
+		}
+	}
+	return out
+}
+
+// AverageColor returns the mean colour of img, as a premultiplied colour. This is
+// a cheap placeholder colour; DominantColors usually gives a more representative
+// result for images with a few large areas of very different colours.
+func AverageColor(img *Image) color.RGBA {
+	var r, g, b, a, n uint64
+	size := img.Size
+	for y := 0; y < size.Y; y++ {
+		for _, c := range img.Vals[y*img.Stride : y*img.Stride+size.X] {
+			r += uint64(c.R)
+			g += uint64(c.G)
+			b += uint64(c.B)
+			a += uint64(c.A)
+			n++
+		}
+	}
+	if n == 0 {
+		return color.RGBA{}
+	}
+	return color.RGBA{
+		R: uint8((r + n/2) / n),
+		G: uint8((g + n/2) / n),
+		B: uint8((b + n/2) / n),
+		A: uint8((a + n/2) / n),
+	}
+}
@@ -0,0 +1,106 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func TestHistograms(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	gen := testimg.RandBlocks{W: 64, H: 32, BlockW: 4, BlockH: 4}
+	img, _ := Convert(gen.RGBA(rng))
+
+	ch := NewChannelHistogram(img)
+	luma := LumaHistogram(img)
+	col := NewColorHistogram(img, 3)
+
+	var nr, na, nl, nc int
+	for i := 0; i < 256; i++ {
+		nr += ch.R[i]
+		na += ch.A[i]
+		nl += luma[i]
+	}
+	for _, n := range col.Counts {
+		nc += n
+	}
+	total := 64 * 32
+	if nr != total || na != total || nl != total || nc != total || col.Total != total {
+		t.Fatal(nr, na, nl, nc, col.Total)
+	}
+	if ch.A[0xff] != total {
+		t.Fatal("expected opaque image")
+	}
+
+	for y := 0; y < img.Size.Y; y++ {
+		for x := 0; x < img.Size.X; x++ {
+			c := img.RGBAAt(x, y)
+			if col.Counts[col.Bin(c)] == 0 {
+				t.Fatal("missing bin for", c)
+			}
+		}
+	}
+}
+
+func TestDominantColors(t *testing.T) {
+	red := color.RGBA{0xe0, 0x10, 0x10, 0xff}
+	nearRed := color.RGBA{0xe8, 0x14, 0x0c, 0xff}
+	blue := color.RGBA{0x10, 0x10, 0xe0, 0xff}
+
+	// 50% red, 20% near-red, 30% blue, plus some transparent pixels which should
+	// not count:
+	img := New(image.Pt(10, 11))
+	for i := range img.Vals {
+		switch {
+		case i < 50:
+			img.Vals[i] = red
+		case i < 70:
+			img.Vals[i] = nearRed
+		case i < 100:
+			img.Vals[i] = blue
+		}
+	}
+
+	for _, perceptual := range []bool{false, true} {
+		dom := DominantColors(img, 5, &DominantOptions{Perceptual: perceptual})
+		if len(dom) != 2 {
+			t.Fatal(perceptual, dom)
+		}
+		if math.Abs(dom[0].Share-0.7) > 1e-9 || math.Abs(dom[1].Share-0.3) > 1e-9 {
+			t.Fatal(perceptual, dom)
+		}
+		if MetricRGB(dom[0].Color, red) > 100 || dom[1].Color != blue {
+			t.Fatal(perceptual, dom)
+		}
+	}
+
+	dom := DominantColors(img, 5, &DominantOptions{Bits: 8, MergeDistance: -1})
+	if len(dom) != 3 || dom[2].Color != nearRed {
+		t.Fatal(dom)
+	}
+
+	if avg := AverageColor(img); avg.A != uint8((100*0xff+55)/110) {
+		t.Fatal(avg)
+	}
+}
+
+func BenchmarkDominantColors(b *testing.B) {
+	rng := rand.New(rand.NewSource(0))
+	gen := testimg.RandBlocks{W: 512, H: 512, BlockW: 8, BlockH: 8}
+	img, _ := Convert(gen.RGBA(rng))
+
+	b.Run("rgb", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			DominantColors(img, 5, nil)
+		}
+	})
+	b.Run("perceptual", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			DominantColors(img, 5, &DominantOptions{Perceptual: true})
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+)
+
+// CountColors counts the unique colours in img, stopping as soon as more than
+// limit have been found. If the result is > limit, the image contains more than
+// limit colours, but the exact count is unknown. If limit is <= 0, all colours are
+// counted.
+func CountColors(img *Image, limit int) int {
+	seen := make(map[uint32]struct{})
+	size := img.Size
+
+	for y := 0; y < size.Y; y++ {
+		row := img.Vals[y*img.Stride : y*img.Stride+size.X]
+		var last uint32
+		for x, c := range row {
+			k := rgbaKey(c)
+			if x > 0 && k == last {
+				continue
+			}
+			last = k
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				if limit > 0 && len(seen) > limit {
+					return len(seen)
+				}
+			}
+		}
+	}
+	return len(seen)
+}
+
+// ExactPalette returns every unique colour in img, in order of first appearance, if
+// there are no more than limit of them. If there are more, ok is false.
+//
+// limit is clamped to 256.
+//
+func ExactPalette(img *Image, limit int) (pal Palette, ok bool) {
+	pal, _, ok = exactPalette(img, limit, false)
+	return pal, ok
+}
+
+// ExactPaletted converts img to an *image.Paletted with no quantisation loss, if img
+// contains no more than limit unique colours. If it contains more, ok is false.
+//
+// The palette is in order of first appearance. limit is clamped to 256.
+//
+// This is handy for writing compact paletted PNGs automatically for flat-colour
+// graphics:
+//
+//	if pimg, _, ok := rgba.ExactPaletted(img, 256); ok {
+//		return png.Encode(w, pimg)
+//	}
+//	return png.Encode(w, img)
+//
+func ExactPaletted(img *Image, limit int) (out *image.Paletted, pal Palette, ok bool) {
+	pal, idx, ok := exactPalette(img, limit, true)
+	if !ok {
+		return nil, nil, false
+	}
+	out = &image.Paletted{
+		Pix:     idx,
+		Stride:  img.Size.X,
+		Rect:    image.Rectangle{Max: img.Size},
+		Palette: pal.ColorPalette(),
+	}
+	return out, pal, true
+}
+
+func exactPalette(img *Image, limit int, withIdx bool) (pal Palette, idx []uint8, ok bool) {
+	if limit <= 0 || limit > 256 {
+		limit = 256
+	}
+
+	size := img.Size
+	at := make(map[uint32]uint8, limit)
+	pal = make(Palette, 0, limit)
+	if withIdx {
+		idx = make([]uint8, size.X*size.Y)
+	}
+
+	var out int
+	for y := 0; y < size.Y; y++ {
+		row := img.Vals[y*img.Stride : y*img.Stride+size.X]
+		var last uint32
+		var lastIdx uint8
+		for x, c := range row {
+			k := rgbaKey(c)
+			if x == 0 || k != last {
+				i, found := at[k]
+				if !found {
+					if len(pal) >= limit {
+						return nil, nil, false
+					}
+					i = uint8(len(pal))
+					at[k] = i
+					pal = append(pal, c)
+				}
+				last, lastIdx = k, i
+			}
+			if withIdx {
+				idx[out] = lastIdx
+			}
+			out++
+		}
+	}
+
+	return pal, idx, true
+}
+
+func rgbaKey(c color.RGBA) uint32 {
+	return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+}
@@ -0,0 +1,94 @@
+package rgba
+
+import (
+	"bytes"
+	"image"
+	"math/rand"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func TestExactPaletted(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	gen := testimg.RandBlocks{W: 100, H: 60, BlockW: 4, BlockH: 4}
+
+	// Paletted images with fewer than 256 colours convert back exactly:
+	src := gen.Paletted(rng, testimg.RandPalette(rng, 40))
+	img, _ := Convert(src)
+
+	if n := CountColors(img, 0); n > 40 || n < 2 {
+		t.Fatal("unexpected count", n)
+	}
+
+	pimg, pal, ok := ExactPaletted(img, 256)
+	if !ok {
+		t.Fatal()
+	}
+	if len(pal) != CountColors(img, 0) {
+		t.Fatal(len(pal))
+	}
+	for y := 0; y < img.Size.Y; y++ {
+		for x := 0; x < img.Size.X; x++ {
+			if pal[pimg.ColorIndexAt(x, y)] != img.RGBAAt(x, y) {
+				t.Fatalf("mismatch at (%d,%d)", x, y)
+			}
+		}
+	}
+
+	// Over the limit:
+	if _, ok := ExactPalette(img, 2); ok {
+		t.Fatal("expected limit to be exceeded")
+	}
+	if n := CountColors(img, 2); n != 3 {
+		t.Fatal("expected early exit at 3, found", n)
+	}
+
+	// Non-tight stride:
+	sub := &Image{Size: img.Size, Stride: img.Stride, Vals: img.Vals}
+	sub.Size.X -= 10
+	if spal, ok := ExactPalette(sub, 256); !ok || len(spal) > len(pal) {
+		t.Fatal()
+	}
+}
+
+func TestExactPalettedText(t *testing.T) {
+	src := testimg.MustParse(`
+		. #00000000
+		r #FF0000
+		g #00FF00
+		h #00FF0080
+
+		rrgg..
+		r.gghh
+		rrrrhh
+	`)
+	img, _ := Convert(src)
+
+	pimg, pal, ok := ExactPaletted(img, 4)
+	if !ok || len(pal) != 4 {
+		t.Fatal(ok, pal)
+	}
+	testimg.AssertImage(t, src, pimg, 0)
+
+	// Dump and re-parse preserves the pixels and the palette order:
+	dump := testimg.Dump(pimg, image.Rectangle{})
+	back := testimg.MustParsePaletted(dump)
+	testimg.AssertImage(t, pimg, back, 0)
+	if !bytes.Equal(back.Pix, pimg.Pix) {
+		t.Fatal("index mismatch:\n" + dump)
+	}
+	if region := testimg.Dump(img, image.Rect(2, 1, 4, 3)); region != "a #00FF00\nb #FF0000\n\naa\nbb\n" {
+		t.Fatalf("unexpected region dump:\n%s", region)
+	}
+}
+
+func BenchmarkCountColors(b *testing.B) {
+	rng := rand.New(rand.NewSource(0))
+	gen := testimg.RandBlocks{W: 512, H: 512, BlockW: 8, BlockH: 8}
+	img, _ := Convert(gen.Paletted(rng, testimg.RandPalette(rng, 256)))
+
+	for i := 0; i < b.N; i++ {
+		BenchInt = CountColors(img, 256)
+	}
+}
@@ -0,0 +1,155 @@
+//go:build go1.18
+//+build go1.18
+
+package rgba
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+// Native fuzzing needs Go 1.18, so these are kept apart from the rest of the tests,
+// which build with the go.mod's Go 1.13. Without -fuzz, they run the seed corpus
+// like any other test.
+
+func FuzzConvert(f *testing.F) {
+	for _, seed := range testimg.FuzzSeeds() {
+		f.Add(seed)
+	}
+	// A subsampled YCbCr input with a negative, odd origin, which FuzzData must move
+	// to non-negative coordinates to produce a valid image:
+	f.Add([]byte("Y\f0\xf4001"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		src := testimg.NewFuzzData(data).Image()
+		img, _ := Convert(src)
+		if img.Size != src.Bounds().Size() {
+			t.Fatalf("expected size %v, found %v", src.Bounds().Size(), img.Size)
+		}
+		testimg.AssertImage(t, src, img, 0)
+	})
+}
+
+func FuzzNRGBAFromNRGBAHex(f *testing.F) {
+	for _, seed := range []string{
+		"#000000", "#FFFFFF", "#12345678", "abcdef", "AbCdEf00",
+		"", "#", "#12345", "#1234567", "#gg0000", "#000000gg", "##000000",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		c, err := NRGBAFromNRGBAHex(s)
+		if err != nil {
+			return
+		}
+
+		// Anything that parses must be 6 or 8 hex digits, which we can print back
+		// out exactly:
+		want := strings.ToUpper(strings.TrimPrefix(s, "#"))
+		got := fmt.Sprintf("%02X%02X%02X", c.R, c.G, c.B)
+		if len(want) == 8 {
+			got += fmt.Sprintf("%02X", c.A)
+		} else if c.A != 0xff {
+			t.Fatalf("expected opaque colour for %q, found %v", s, c)
+		}
+		if got != want {
+			t.Fatalf("expected %q, found %q", want, got)
+		}
+
+		pc, err := FromNRGBAHex(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := color.RGBAModel.Convert(c).(color.RGBA); pc != want {
+			t.Fatalf("expected %v, found %v", want, pc)
+		}
+	})
+}
+
+func FuzzNRGBAFromCSS(f *testing.F) {
+	for _, seed := range []string{
+		"", "#abc", "#abcd", "#aabbcc", "#aabbccdd", "red", "Transparent", "rebeccapurple",
+		"rgb(1, 2, 3)", "rgba(1,2,3,0.5)", "rgb(10% 20% 30% / 40%)", "rgb(none 1e2 3)",
+		"hsl(120deg 50% 50%)", "hsla(0.5turn, 100%, 25%, .5)", "hwb(90 10% 10%)", "hwb(1rad 60% 60% / 0)",
+		"rgb(1, 2 / 3)", "hsl()", "rgb(1 2 3 4)", "nope(1 2 3)",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		c, err := NRGBAFromCSS(s)
+		if err != nil {
+			return
+		}
+
+		// Anything that parses must format to something no longer than the hex, that
+		// parses back to the same colour:
+		css := NRGBAToCSS(c)
+		back, err := NRGBAFromCSS(css)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back != c {
+			t.Fatalf("expected %v, found %v via %q", c, back, css)
+		}
+		if len(css) > 9 {
+			t.Fatalf("expected at most 9 characters, found %q", css)
+		}
+	})
+}
+
+func FuzzToNRGBAHex(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 0})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{0x10, 0x20, 0x30, 0x40})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		col := testimg.NewFuzzData(data).RGBA()
+		hex := ToNRGBAHex(col)
+		back, err := FromNRGBAHex(hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// As in TestRGBAHexRand, 8-bit NRGBA to RGBA is not quite accurate.
+		if absDiff8(back.R, col.R) > 1 || absDiff8(back.G, col.G) > 1 ||
+			absDiff8(back.B, col.B) > 1 || back.A != col.A {
+			t.Fatalf("back %#v != col %#v via %q", back, col, hex)
+		}
+	})
+}
+
+func FuzzUnmarshalIndex(f *testing.F) {
+	indexer := NewRGBPrecacheIndexer(nil)
+	for _, sz := range []int{1, 2, 16, 256} {
+		pal := ConvertPalette(testimg.RandPalette(testimg.NewRNG(uint64(sz)), sz))
+		data := indexer.IndexRGBAPalette(pal).(IndexMarshaler).MarshalIndex()
+		f.Add(data)
+		f.Add(data[:len(data)/2])
+	}
+	f.Add([]byte{})
+	f.Add([]byte{1, 0, 0, 0, 0, 0, 0, 0xff})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		idx, err := indexer.(IndexUnmarshaler).UnmarshalIndex(data)
+		if err != nil {
+			return
+		}
+
+		// Anything that unmarshals must marshal again to an equivalent index:
+		again, err := indexer.(IndexUnmarshaler).UnmarshalIndex(idx.(IndexMarshaler).MarshalIndex())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for c := 0; c < 32768; c++ {
+			col := color.RGBA{uint8(c>>10) << 3, uint8(c>>5) << 3, uint8(c) << 3, 0xff}
+			c1, i1 := idx.NearestRGBA(col)
+			c2, i2 := again.NearestRGBA(col)
+			if c1 != c2 || i1 != i2 {
+				t.Fatalf("%v: expected %v (%d), found %v (%d)", col, c1, i1, c2, i2)
+			}
+		}
+	})
+}
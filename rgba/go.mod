@@ -3,3 +3,5 @@ module github.com/shabbyrobe/imgx/rgba
 go 1.13
 
 require github.com/shabbyrobe/imgx/testimg v0.0.0-20200120051032-40288d41177c
+
+replace github.com/shabbyrobe/imgx/testimg => ../testimg
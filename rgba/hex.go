@@ -6,7 +6,7 @@ import (
 )
 
 func NRGBAFromNRGBAHex(s string) (c color.NRGBA, err error) {
-	if s[0] == '#' {
+	if len(s) > 0 && s[0] == '#' {
 		s = s[1:]
 	}
 
@@ -24,6 +24,9 @@ func NRGBAFromNRGBAHex(s string) (c color.NRGBA, err error) {
 	a := int(0xff)
 	if hasAlpha {
 		a = (hexVals[s[6]] << 4) | hexVals[s[7]]
+		if a < 0 {
+			return c, fmt.Errorf("rgba: invalid hex %q", s)
+		}
 	}
 	c = color.NRGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: uint8(a)}
 	return c, nil
@@ -34,13 +37,7 @@ func FromNRGBAHex(s string) (c color.RGBA, err error) {
 	if err != nil {
 		return c, err
 	}
-	a := uint32(nr.A)
-	return color.RGBA{
-		R: uint8(uint32(nr.R) * a / 0xff),
-		G: uint8(uint32(nr.G) * a / 0xff),
-		B: uint8(uint32(nr.B) * a / 0xff),
-		A: uint8(a),
-	}, nil
+	return premultiplyNRGBA(nr), nil
 }
 
 // If len(b) < 9, this will panic.
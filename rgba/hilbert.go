@@ -0,0 +1,52 @@
+package rgba
+
+import "image/color"
+
+// hilbertRGB returns the distance along a 3D Hilbert curve through the 8-bit RGB
+// cube for c, discarding alpha. Colours that are close together on the curve tend to
+// be close together in the cube, which makes it a good ordering for palettes that
+// aren't easily ordered by a single value like luminance or hue.
+//
+// This uses John Skilling's transpose method, "Programming the Hilbert curve" (2004).
+func hilbertRGB(c color.RGBA) uint32 {
+	const bits = 8
+
+	x := [3]uint32{uint32(c.R), uint32(c.G), uint32(c.B)}
+
+	// Inverse undo excess work:
+	for q := uint32(1 << (bits - 1)); q > 1; q >>= 1 {
+		p := q - 1
+		for i := 0; i < 3; i++ {
+			if x[i]&q != 0 {
+				x[0] ^= p // invert
+			} else {
+				t := (x[0] ^ x[i]) & p // exchange
+				x[0] ^= t
+				x[i] ^= t
+			}
+		}
+	}
+
+	// Gray encode:
+	for i := 1; i < 3; i++ {
+		x[i] ^= x[i-1]
+	}
+	var t uint32
+	for q := uint32(1 << (bits - 1)); q > 1; q >>= 1 {
+		if x[2]&q != 0 {
+			t ^= q - 1
+		}
+	}
+	for i := 0; i < 3; i++ {
+		x[i] ^= t
+	}
+
+	// Interleave the transposed bits into a single index, most significant first:
+	var out uint32
+	for b := bits - 1; b >= 0; b-- {
+		for i := 0; i < 3; i++ {
+			out = (out << 1) | ((x[i] >> uint(b)) & 1)
+		}
+	}
+	return out
+}
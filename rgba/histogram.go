@@ -0,0 +1,132 @@
+package rgba
+
+import (
+	"image/color"
+)
+
+// ChannelHistogram contains a 256-bin histogram for each channel of an image.
+type ChannelHistogram struct {
+	R, G, B, A [256]int
+}
+
+// NewChannelHistogram counts the values of each channel in img. Values are
+// premultiplied, as they are stored in img.
+func NewChannelHistogram(img *Image) *ChannelHistogram {
+	var h ChannelHistogram
+	size := img.Size
+	for y := 0; y < size.Y; y++ {
+		for _, c := range img.Vals[y*img.Stride : y*img.Stride+size.X] {
+			h.R[c.R]++
+			h.G[c.G]++
+			h.B[c.B]++
+			h.A[c.A]++
+		}
+	}
+	return &h
+}
+
+// LumaHistogram counts the luma (Rec. 709 coefficients on the gamma-encoded values)
+// of each pixel in img.
+func LumaHistogram(img *Image) (h [256]int) {
+	size := img.Size
+	for y := 0; y < size.Y; y++ {
+		for _, c := range img.Vals[y*img.Stride : y*img.Stride+size.X] {
+			h[(luma709(c)+5000)/10000]++
+		}
+	}
+	return h
+}
+
+// ColorHistogram is a 3D histogram of the RGB values in an image. Each channel is
+// truncated to Bits bits, so there are 1<<(Bits*3) bins.
+//
+// Alongside the count of each bin, the sum of each channel of the colours that fell
+// into it are kept, so the mean colour of the bin can be found with Mean.
+//
+// Partially transparent pixels are un-premultiplied before they are counted, and
+// fully transparent pixels are not counted at all.
+//
+type ColorHistogram struct {
+	Bits   uint
+	Total  int
+	Counts []int
+	sums   [][3]uint64
+}
+
+// NewColorHistogram builds a ColorHistogram of img. bits must be between 1 and 8;
+// it is clamped if not. 8 bits uses a lot of memory (16M bins).
+func NewColorHistogram(img *Image, bits uint) *ColorHistogram {
+	if bits < 1 {
+		bits = 1
+	} else if bits > 8 {
+		bits = 8
+	}
+
+	bins := 1 << (bits * 3)
+	h := &ColorHistogram{
+		Bits:   bits,
+		Counts: make([]int, bins),
+		sums:   make([][3]uint64, bins),
+	}
+
+	size := img.Size
+	for y := 0; y < size.Y; y++ {
+		for _, c := range img.Vals[y*img.Stride : y*img.Stride+size.X] {
+			if c.A == 0 {
+				continue
+			} else if c.A != 0xff {
+				c = unpremultiply(c)
+			}
+			bin := h.Bin(c)
+			h.Counts[bin]++
+			h.Total++
+			s := &h.sums[bin]
+			s[0] += uint64(c.R)
+			s[1] += uint64(c.G)
+			s[2] += uint64(c.B)
+		}
+	}
+	return h
+}
+
+// Bin returns the index into Counts of the bin that c falls into.
+func (h *ColorHistogram) Bin(c color.RGBA) int {
+	shift := 8 - h.Bits
+	return int(c.R>>shift)<<(h.Bits*2) | int(c.G>>shift)<<h.Bits | int(c.B>>shift)
+}
+
+// Mean returns the mean colour of the pixels that fell into bin, which is opaque. If
+// the bin is empty, the zero colour is returned.
+func (h *ColorHistogram) Mean(bin int) color.RGBA {
+	n := uint64(h.Counts[bin])
+	if n == 0 {
+		return color.RGBA{}
+	}
+	s := h.sums[bin]
+	return color.RGBA{
+		R: uint8((s[0] + n/2) / n),
+		G: uint8((s[1] + n/2) / n),
+		B: uint8((s[2] + n/2) / n),
+		A: 0xff,
+	}
+}
+
+// unpremultiply returns the non-premultiplied equivalent of c as a color.RGBA.
+// Channels that are invalid for the alpha (greater than it) are clamped.
+func unpremultiply(c color.RGBA) color.RGBA {
+	if c.A == 0xff || c.A == 0 {
+		return c
+	}
+	a := uint32(c.A)
+	r, g, b := uint32(c.R)*0xff/a, uint32(c.G)*0xff/a, uint32(c.B)*0xff/a
+	if r > 0xff {
+		r = 0xff
+	}
+	if g > 0xff {
+		g = 0xff
+	}
+	if b > 0xff {
+		b = 0xff
+	}
+	return color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: c.A}
+}
@@ -69,36 +69,9 @@ func (p *Image) Set(x, y int, c color.Color) {
 		return
 	}
 
-	switch c := c.(type) {
-	case color.RGBA:
-		p.Vals[y*p.Stride+x] = c
-
-	case color.RGBA64:
-		p.Vals[y*p.Stride+x] = color.RGBA{
-			R: uint8(c.R >> 8),
-			G: uint8(c.G >> 8),
-			B: uint8(c.B >> 8),
-			A: uint8(c.A >> 8),
-		}
-
-	case color.NRGBA:
-		a32 := uint32(c.A)
-		p.Vals[y*p.Stride+x] = color.RGBA{
-			R: uint8(uint32(c.R) * a32 / 0xff),
-			G: uint8(uint32(c.G) * a32 / 0xff),
-			B: uint8(uint32(c.B) * a32 / 0xff),
-			A: c.A,
-		}
-
-	default:
-		r, g, b, a := c.RGBA()
-		p.Vals[y*p.Stride+x] = color.RGBA{
-			R: uint8(r >> 8),
-			G: uint8(g >> 8),
-			B: uint8(b >> 8),
-			A: uint8(a >> 8),
-		}
-	}
+	// Like color.NRGBA, NRGBA colours are premultiplied in gamma-encoded values;
+	// see ToLinear for linear light.
+	p.Vals[y*p.Stride+x] = colorToRGBA(c)
 }
 
 func (p *Image) SetRGBA(x, y int, c color.RGBA) {
@@ -1,6 +1,9 @@
 package rgba
 
-import "image/color"
+import (
+	"image"
+	"image/color"
+)
 
 // Indexer is used to create an Index of a Palette. The Index allows
 // lookups of nearest-neighbour colour values.
@@ -22,6 +25,18 @@ type Index interface {
 	NearestRGBAColor(c color.RGBA) color.RGBA
 }
 
+// IndexMapper may be implemented by an Index to map many colours at once, which
+// avoids the cost of calling NearestRGBAIndex through an interface for every pixel.
+//
+// Implementations may spread the work across multiple goroutines. Callers should not
+// use IndexMapper directly, use MapImage and MapVals instead, which will fall back to
+// a generic implementation if the Index does not implement IndexMapper.
+//
+type IndexMapper interface {
+	MapImage(src *Image, dst *image.Paletted)
+	MapVals(src []color.RGBA, dst []uint8)
+}
+
 // IndexUnmarshaler may be implemented by an Indexer to allow unserializing
 // a binary representation of an Index.
 //
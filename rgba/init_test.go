@@ -1,6 +1,7 @@
 package rgba
 
 import (
+	"flag"
 	"fmt"
 	"image/color"
 	"image/color/palette"
@@ -9,6 +10,9 @@ import (
 	"github.com/shabbyrobe/imgx/testimg"
 )
 
+// Regenerates the fixtures for testimg.AssertGolden.
+var _ = flag.Bool("update", false, "regenerate golden images")
+
 type paletteCase struct {
 	name string
 	pal  color.Palette
@@ -60,3 +64,45 @@ func rgbNearestEuclideanIndex(p Palette, c color.RGBA) int {
 func rgbNearestEuclidean(p Palette, c color.RGBA) color.RGBA {
 	return p[rgbNearestEuclideanIndex(p, c)]
 }
+
+type recipeCase struct {
+	name   string
+	recipe testimg.Recipe
+}
+
+// smoothRecipes returns recipes for smooth, photographic-like content to go alongside
+// the blocky testimg.RandBlocks.
+func smoothRecipes(w, h int) []recipeCase {
+	return []recipeCase{
+		{"lineargradient", testimg.LinearGradient{W: w, H: h, Angle: 30}},
+		{"radialgradient", testimg.RadialGradient{W: w, H: h}},
+		{"checkerboard", testimg.Checkerboard{W: w, H: h, CellW: 3, CellH: 5}},
+		{"whitenoise", testimg.WhiteNoise{W: w, H: h}},
+		{"valuenoise", testimg.ValueNoise{W: w, H: h, Scale: 16, Octaves: 3}},
+		{"perlin", testimg.PerlinNoise{W: w, H: h, Octaves: 4}},
+		{"simplex", testimg.PerlinNoise{W: w, H: h, Octaves: 4, Simplex: true}},
+		{"plasma", testimg.Plasma{W: w, H: h}},
+		{"circles", testimg.RandCircles{W: w, H: h, Alpha: true}},
+		{"polygons", testimg.RandPolygons{W: w, H: h, Sides: 7}},
+		{"alpharamp", testimg.AlphaRamp{Base: testimg.Plasma{W: w, H: h}}},
+	}
+}
+
+// hardPaletteCases returns palettes aimed at the cases indexers find hard: duplicate
+// entries, tight clusters, greys, and mixtures of opaque and transparent colours.
+func hardPaletteCases(rng testimg.RNG) []paletteCase {
+	seeds := []color.Color{
+		color.RGBA{0x20, 0x40, 0x80, 0xff},
+		color.RGBA{0xf0, 0xf0, 0x10, 0xff},
+	}
+	return []paletteCase{
+		{"dupes", testimg.ColorDist{Alpha: testimg.AlphaOpaque, Unique: 8}.Palette(rng, 64)},
+		{"alldupes", testimg.ColorDist{Unique: 1}.Palette(rng, 16)},
+		{"clustered", testimg.ColorDist{Alpha: testimg.AlphaOpaque, Seeds: seeds, Spread: 3}.Palette(rng, 64)},
+		{"gray", testimg.ColorDist{Alpha: testimg.AlphaOpaque, Gray: true, Spread: -1}.Palette(rng, 64)},
+		{"neargray", testimg.ColorDist{Gray: true, Spread: 2}.Palette(rng, 128)},
+		{"transparent", testimg.ColorDist{Alpha: testimg.AlphaOpaque, Transparent: 0.25}.Palette(rng, 64)},
+		{"alphaset", testimg.ColorDist{Alpha: testimg.AlphaSet, Alphas: []uint8{0, 0x80, 0xff}}.Palette(rng, 64)},
+		{"premul", testimg.ColorDist{}.Palette(rng, 256)},
+	}
+}
@@ -0,0 +1,184 @@
+package rgba
+
+import (
+	"image/color"
+	"math"
+)
+
+// srgbToLinearTable maps 8-bit gamma-encoded sRGB values to linear light in [0, 1].
+var srgbToLinearTable [256]float64
+
+func init() {
+	for i := range srgbToLinearTable {
+		srgbToLinearTable[i] = srgbToLinear(float64(i) / 255)
+	}
+}
+
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// D65 reference white, scaled so Y == 1.
+const (
+	d65X = 0.95047
+	d65Y = 1.0
+	d65Z = 1.08883
+)
+
+// labFromRGBA converts the RGB components of c to CIELAB, using the D65 white
+// point. Alpha is ignored; premultiplied colours are treated as if composited over
+// black.
+func labFromRGBA(c color.RGBA) (l, a, b float64) {
+	x, y, z := linearRGBToXYZ.mul(srgbToLinearTable[c.R], srgbToLinearTable[c.G], srgbToLinearTable[c.B])
+	return labFromXYZ(x, y, z)
+}
+
+// linearRGBToXYZ converts linear sRGB to CIE XYZ, with the D65 white point.
+var linearRGBToXYZ = mat3{
+	0.4124564, 0.3575761, 0.1804375,
+	0.2126729, 0.7151522, 0.0721750,
+	0.0193339, 0.1191920, 0.9503041,
+}
+
+func labFromXYZ(x, y, z float64) (l, a, b float64) {
+	fx, fy, fz := labF(x/d65X), labF(y/d65Y), labF(z/d65Z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+func labToXYZ(l, a, b float64) (x, y, z float64) {
+	fy := (l + 16) / 116
+	fx, fz := fy+a/500, fy-b/200
+	return d65X * labFInv(fx), d65Y * labFInv(fy), d65Z * labFInv(fz)
+}
+
+func labF(t float64) float64 {
+	const delta = 6.0 / 29
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4.0/29
+}
+
+func labFInv(t float64) float64 {
+	const delta = 6.0 / 29
+	if t > delta {
+		return t * t * t
+	}
+	return 3 * delta * delta * (t - 4.0/29)
+}
+
+// mat3 is a row-major 3x3 matrix, for converting between colour spaces.
+type mat3 [9]float64
+
+func (m *mat3) mul(a, b, c float64) (x, y, z float64) {
+	return m[0]*a + m[1]*b + m[2]*c,
+		m[3]*a + m[4]*b + m[5]*c,
+		m[6]*a + m[7]*b + m[8]*c
+}
+
+// inverse returns the inverse of m. Inverting the forward matrices, rather than
+// using published inverses, which are rounded, keeps round trips exact.
+func (m *mat3) inverse() mat3 {
+	det := m[0]*(m[4]*m[8]-m[5]*m[7]) -
+		m[1]*(m[3]*m[8]-m[5]*m[6]) +
+		m[2]*(m[3]*m[7]-m[4]*m[6])
+	return mat3{
+		(m[4]*m[8] - m[5]*m[7]) / det,
+		(m[2]*m[7] - m[1]*m[8]) / det,
+		(m[1]*m[5] - m[2]*m[4]) / det,
+		(m[5]*m[6] - m[3]*m[8]) / det,
+		(m[0]*m[8] - m[2]*m[6]) / det,
+		(m[2]*m[3] - m[0]*m[5]) / det,
+		(m[3]*m[7] - m[4]*m[6]) / det,
+		(m[1]*m[6] - m[0]*m[7]) / det,
+		(m[0]*m[4] - m[1]*m[3]) / det,
+	}
+}
+
+// deltaE2000 returns the CIEDE2000 colour difference between two CIELAB colours.
+//
+// See "The CIEDE2000 Color-Difference Formula: Implementation Notes, Supplementary
+// Test Data, and Mathematical Observations", Sharma, Wu and Dalal (2005).
+func deltaE2000(l1, a1, b1, l2, a2, b2 float64) float64 {
+	const deg = math.Pi / 180
+
+	c1 := math.Hypot(a1, b1)
+	c2 := math.Hypot(a2, b2)
+	cbar := (c1 + c2) / 2
+	cbar7 := math.Pow(cbar, 7)
+	g := 0.5 * (1 - math.Sqrt(cbar7/(cbar7+6103515625))) // 25^7
+
+	a1p, a2p := (1+g)*a1, (1+g)*a2
+	c1p, c2p := math.Hypot(a1p, b1), math.Hypot(a2p, b2)
+
+	h1p := labHue(a1p, b1)
+	h2p := labHue(a2p, b2)
+
+	dLp := l2 - l1
+	dCp := c2p - c1p
+
+	var dhp float64
+	if c1p*c2p != 0 {
+		dhp = h2p - h1p
+		if dhp > 180 {
+			dhp -= 360
+		} else if dhp < -180 {
+			dhp += 360
+		}
+	}
+	dHp := 2 * math.Sqrt(c1p*c2p) * math.Sin(dhp*deg/2)
+
+	lbarp := (l1 + l2) / 2
+	cbarp := (c1p + c2p) / 2
+
+	hbarp := h1p + h2p
+	if c1p*c2p != 0 {
+		if math.Abs(h1p-h2p) > 180 {
+			if hbarp < 360 {
+				hbarp += 360
+			} else {
+				hbarp -= 360
+			}
+		}
+		hbarp /= 2
+	}
+
+	t := 1 -
+		0.17*math.Cos((hbarp-30)*deg) +
+		0.24*math.Cos(2*hbarp*deg) +
+		0.32*math.Cos((3*hbarp+6)*deg) -
+		0.20*math.Cos((4*hbarp-63)*deg)
+
+	dTheta := 30 * math.Exp(-((hbarp-275)/25)*((hbarp-275)/25))
+	cbarp7 := math.Pow(cbarp, 7)
+	rc := 2 * math.Sqrt(cbarp7/(cbarp7+6103515625))
+	lbarp50 := (lbarp - 50) * (lbarp - 50)
+	sl := 1 + (0.015*lbarp50)/math.Sqrt(20+lbarp50)
+	sc := 1 + 0.045*cbarp
+	sh := 1 + 0.015*cbarp*t
+	rt := -math.Sin(2*dTheta*deg) * rc
+
+	fl, fc, fh := dLp/sl, dCp/sc, dHp/sh
+	return math.Sqrt(fl*fl + fc*fc + fh*fh + rt*fc*fh)
+}
+
+func labHue(a, b float64) float64 {
+	if a == 0 && b == 0 {
+		return 0
+	}
+	h := math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
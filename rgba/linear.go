@@ -0,0 +1,346 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Light selects whether an operation works on gamma-encoded sRGB values, as they
+// are stored in an Image, or in linear light.
+//
+// Averaging gamma-encoded values, which is what most image code does, darkens the
+// result: a 50/50 mix of black and white comes out at 0x80, which looks much darker
+// than a fine checkerboard of the two. Working in linear light gives 0xBC, which
+// matches it.
+//
+type Light int
+
+const (
+	GammaLight Light = iota
+	LinearLight
+)
+
+// LinearRGBA is a colour in linear-light sRGB, with 16 bits per channel. Unlike
+// color.RGBA, which premultiplies the gamma-encoded values, the channels are
+// premultiplied in linear light, so they can be summed and scaled directly.
+type LinearRGBA struct {
+	R, G, B, A uint16
+}
+
+var LinearModel color.Model = color.ModelFunc(linearModel)
+
+func linearModel(c color.Color) color.Color {
+	if c, ok := c.(LinearRGBA); ok {
+		return c
+	}
+	return ToLinearRGBA(color.RGBAModel.Convert(c).(color.RGBA))
+}
+
+// RGBA returns the colour as premultiplied, gamma-encoded sRGB, so it is only as
+// precise as the 8-bit color.RGBA that FromLinearRGBA returns.
+func (c LinearRGBA) RGBA() (r, g, b, a uint32) {
+	return FromLinearRGBA(c).RGBA()
+}
+
+var (
+	// srgb8ToLinear16 maps an 8-bit gamma-encoded value to 16-bit linear light.
+	srgb8ToLinear16 [256]uint16
+
+	// linear16ToSRGB8 maps a 16-bit linear value to the nearest 8-bit gamma-encoded
+	// value, measured in the gamma-encoded space.
+	linear16ToSRGB8 [65536]uint8
+)
+
+func init() {
+	for i := range srgb8ToLinear16 {
+		srgb8ToLinear16[i] = uint16(math.Round(srgbToLinear(float64(i)/0xff) * 0xffff))
+	}
+
+	// Fill each run of linear16ToSRGB8 up to the midpoint between two encoded values,
+	// rather than calling math.Pow 65536 times:
+	var lo int
+	for i := 0; i < 0xff; i++ {
+		hi := int(math.Ceil(srgbToLinear((float64(i)+0.5)/0xff) * 0xffff))
+		for ; lo < hi; lo++ {
+			linear16ToSRGB8[lo] = uint8(i)
+		}
+	}
+	for ; lo < len(linear16ToSRGB8); lo++ {
+		linear16ToSRGB8[lo] = 0xff
+	}
+}
+
+// ToLinearRGBA converts premultiplied, gamma-encoded c to linear light. Converting
+// the result back with FromLinearRGBA returns c exactly.
+func ToLinearRGBA(c color.RGBA) LinearRGBA {
+	switch c.A {
+	case 0:
+		return LinearRGBA{}
+	case 0xff:
+		return LinearRGBA{srgb8ToLinear16[c.R], srgb8ToLinear16[c.G], srgb8ToLinear16[c.B], 0xffff}
+	}
+
+	a := uint32(c.A)
+	lin := func(v uint8) uint16 {
+		n := (uint32(v)*0xff + a/2) / a
+		if n > 0xff {
+			n = 0xff
+		}
+		return uint16((uint32(srgb8ToLinear16[n])*a + 0x7f) / 0xff)
+	}
+	return LinearRGBA{lin(c.R), lin(c.G), lin(c.B), uint16(a * 0x101)}
+}
+
+// FromLinearRGBA converts c from linear light to premultiplied, gamma-encoded
+// color.RGBA. Colour channels greater than alpha are clamped.
+func FromLinearRGBA(c LinearRGBA) color.RGBA {
+	a8 := uint32((uint32(c.A) + 0x80) / 0x101)
+	switch a8 {
+	case 0:
+		return color.RGBA{}
+	case 0xff:
+		if c.A == 0xffff {
+			return color.RGBA{linear16ToSRGB8[c.R], linear16ToSRGB8[c.G], linear16ToSRGB8[c.B], 0xff}
+		}
+	}
+
+	a := uint32(c.A)
+	enc := func(v uint16) uint8 {
+		n := (uint32(v)*0xffff + a/2) / a
+		if n > 0xffff {
+			n = 0xffff
+		}
+		return uint8((uint32(linear16ToSRGB8[n])*a8 + 0x7f) / 0xff)
+	}
+	return color.RGBA{enc(c.R), enc(c.G), enc(c.B), uint8(a8)}
+}
+
+// LinearImage is an image of LinearRGBA pixels, laid out like Image.
+type LinearImage struct {
+	Size   image.Point
+	Stride int
+	Vals   []LinearRGBA
+}
+
+var _ image.Image = &LinearImage{}
+
+func NewLinear(size image.Point) *LinearImage {
+	return &LinearImage{
+		Size:   size,
+		Stride: size.X,
+		Vals:   make([]LinearRGBA, size.X*size.Y),
+	}
+}
+
+// ToLinear converts img to linear light. FromLinear converts it back exactly.
+func ToLinear(img *Image) *LinearImage {
+	out := NewLinear(img.Size)
+	for y := 0; y < img.Size.Y; y++ {
+		inVals := img.Vals[y*img.Stride : y*img.Stride+img.Size.X]
+		outVals := out.Vals[y*out.Stride : y*out.Stride+img.Size.X]
+		for x, c := range inVals {
+			outVals[x] = ToLinearRGBA(c)
+		}
+	}
+	return out
+}
+
+// FromLinear converts img from linear light back to gamma-encoded sRGB.
+func FromLinear(img *LinearImage) *Image {
+	out := New(img.Size)
+	for y := 0; y < img.Size.Y; y++ {
+		inVals := img.Vals[y*img.Stride : y*img.Stride+img.Size.X]
+		outVals := out.Vals[y*out.Stride : y*out.Stride+img.Size.X]
+		for x, c := range inVals {
+			outVals[x] = FromLinearRGBA(c)
+		}
+	}
+	return out
+}
+
+func (p *LinearImage) ColorModel() color.Model {
+	return LinearModel
+}
+
+func (p *LinearImage) Bounds() image.Rectangle {
+	return image.Rectangle{Max: p.Size}
+}
+
+func (p *LinearImage) At(x, y int) color.Color {
+	return p.LinearAt(x, y)
+}
+
+func (p *LinearImage) PixOffset(x, y int) int {
+	return y*p.Stride + x
+}
+
+func (p *LinearImage) LinearAt(x, y int) (c LinearRGBA) {
+	if x >= p.Size.X || y >= p.Size.Y {
+		return c
+	}
+	return p.Vals[y*p.Stride+x]
+}
+
+func (p *LinearImage) Set(x, y int, c color.Color) {
+	if x >= p.Size.X || y >= p.Size.Y {
+		return
+	}
+	p.Vals[y*p.Stride+x] = LinearModel.Convert(c).(LinearRGBA)
+}
+
+func (p *LinearImage) SetLinear(x, y int, c LinearRGBA) {
+	if x >= p.Size.X || y >= p.Size.Y {
+		return
+	}
+	p.Vals[y*p.Stride+x] = c
+}
+
+// lightVals returns c as premultiplied channels in [0, 1], in the given light.
+func lightVals(c color.RGBA, light Light) [4]float32 {
+	if light == LinearLight {
+		l := ToLinearRGBA(c)
+		return [4]float32{float32(l.R) / 0xffff, float32(l.G) / 0xffff, float32(l.B) / 0xffff, float32(l.A) / 0xffff}
+	}
+	return [4]float32{float32(c.R) / 0xff, float32(c.G) / 0xff, float32(c.B) / 0xff, float32(c.A) / 0xff}
+}
+
+// lightColor is the inverse of lightVals. Values are clamped to [0, 1], and colour
+// channels to alpha.
+func lightColor(v [4]float32, light Light) color.RGBA {
+	scale := float32(0xff)
+	if light == LinearLight {
+		scale = 0xffff
+	}
+	var out [4]uint16
+	for i := 3; i >= 0; i-- {
+		f := v[i]
+		if f < 0 {
+			f = 0
+		} else if f > 1 {
+			f = 1
+		}
+		out[i] = uint16(f*scale + 0.5)
+		if out[i] > out[3] {
+			out[i] = out[3]
+		}
+	}
+	if light == LinearLight {
+		return FromLinearRGBA(LinearRGBA{out[0], out[1], out[2], out[3]})
+	}
+	return color.RGBA{uint8(out[0]), uint8(out[1]), uint8(out[2]), uint8(out[3])}
+}
+
+// Mix returns the weighted average of a and b, with t from 0 (all a) to 1 (all b),
+// in the given light.
+func Mix(a, b color.RGBA, t float64, light Light) color.RGBA {
+	av, bv := lightVals(a, light), lightVals(b, light)
+	ft := float32(t)
+	for i := range av {
+		av[i] += (bv[i] - av[i]) * ft
+	}
+	return lightColor(av, light)
+}
+
+// Over composites src over dst in place, using the Porter-Duff "over" operator in
+// the given light. src's origin is placed at 'at' in dst; pixels that fall outside
+// dst are ignored.
+func Over(dst, src *Image, at image.Point, light Light) {
+	r := src.Bounds().Add(at).Intersect(dst.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			s := src.Vals[(y-at.Y)*src.Stride+(x-at.X)]
+			if s.A == 0 {
+				continue
+			}
+			d := &dst.Vals[y*dst.Stride+x]
+			if s.A == 0xff {
+				*d = s
+				continue
+			}
+			sv, dv := lightVals(s, light), lightVals(*d, light)
+			for i := range sv {
+				sv[i] += dv[i] * (1 - sv[3])
+			}
+			*d = lightColor(sv, light)
+		}
+	}
+}
+
+// Resample scales src to size with a box filter, which averages every source pixel
+// that each destination pixel covers, weighted by how much of it is covered. This is
+// the best choice for downscaling; upscaling gives blocks, like nearest-neighbour.
+//
+// Averaging in GammaLight darkens fine detail, most obviously in high-contrast
+// textures and text. LinearLight does not.
+//
+// If either side of size is negative, the result is empty.
+//
+func Resample(src *Image, size image.Point, light Light) *Image {
+	if size.X < 0 || size.Y < 0 {
+		return New(image.Point{})
+	}
+	out := New(size)
+	if size.X == 0 || size.Y == 0 || src.Size.X <= 0 || src.Size.Y <= 0 {
+		return out
+	}
+
+	in := make([][4]float32, src.Size.X*src.Size.Y)
+	for y := 0; y < src.Size.Y; y++ {
+		for x, c := range src.Vals[y*src.Stride : y*src.Stride+src.Size.X] {
+			in[y*src.Size.X+x] = lightVals(c, light)
+		}
+	}
+
+	// Resample rows, then columns, of the intermediate image:
+	xw := boxWeights(src.Size.X, size.X)
+	yw := boxWeights(src.Size.Y, size.Y)
+	mid := make([][4]float32, size.X*src.Size.Y)
+	for y := 0; y < src.Size.Y; y++ {
+		for x, ws := range xw {
+			var v [4]float32
+			for _, w := range ws {
+				c := in[y*src.Size.X+w.idx]
+				for i := range v {
+					v[i] += c[i] * w.weight
+				}
+			}
+			mid[y*size.X+x] = v
+		}
+	}
+	for y, ws := range yw {
+		for x := 0; x < size.X; x++ {
+			var v [4]float32
+			for _, w := range ws {
+				c := mid[w.idx*size.X+x]
+				for i := range v {
+					v[i] += c[i] * w.weight
+				}
+			}
+			out.Vals[y*out.Stride+x] = lightColor(v, light)
+		}
+	}
+	return out
+}
+
+type boxWeight struct {
+	idx    int
+	weight float32
+}
+
+// boxWeights returns, for each of the 'to' destination pixels along one axis, the
+// source pixels it covers and the share of each, which sum to 1.
+func boxWeights(from, to int) [][]boxWeight {
+	out := make([][]boxWeight, to)
+	scale := float64(from) / float64(to)
+	for i := range out {
+		lo, hi := float64(i)*scale, float64(i+1)*scale
+		for j := int(lo); j < from && float64(j) < hi; j++ {
+			w := math.Min(hi, float64(j+1)) - math.Max(lo, float64(j))
+			if w > 0 {
+				out[i] = append(out[i], boxWeight{j, float32(w / scale)})
+			}
+		}
+	}
+	return out
+}
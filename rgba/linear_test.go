@@ -0,0 +1,184 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func TestLinearTables(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		lin := srgb8ToLinear16[i]
+		if back := linear16ToSRGB8[lin]; back != uint8(i) {
+			t.Fatalf("%d: expected %d, found %d via %d", i, i, back, lin)
+		}
+	}
+
+	// Every 16-bit linear value must map to the nearest encoded value:
+	for i := 0; i < 65536; i += 7 {
+		enc := linearToSRGB(float64(i)/0xffff) * 0xff
+		if d := math.Abs(float64(linear16ToSRGB8[i]) - enc); d > 0.5+1e-9 {
+			t.Fatalf("%d: expected %f, found %d", i, enc, linear16ToSRGB8[i])
+		}
+	}
+}
+
+func TestLinearRGBARoundTrip(t *testing.T) {
+	// Every opaque value, and every premultiplied value for a spread of alphas:
+	for _, a := range []int{0xff, 0xfe, 0x80, 0x7f, 0x10, 0x03, 0x02, 0x01} {
+		for v := 0; v <= a; v++ {
+			c := color.RGBA{uint8(v), uint8(a - v), uint8(v / 2), uint8(a)}
+			if back := FromLinearRGBA(ToLinearRGBA(c)); back != c {
+				t.Fatalf("expected %v, found %v via %v", c, back, ToLinearRGBA(c))
+			}
+		}
+	}
+
+	rng := testimg.NewRNG(0)
+	for i := 0; i < 100000; i++ {
+		c := testimg.RandRGBA(rng)
+		lin := ToLinearRGBA(c)
+		if back := FromLinearRGBA(lin); back != c {
+			t.Fatalf("expected %v, found %v via %v", c, back, lin)
+		}
+		if lin.R > lin.A || lin.G > lin.A || lin.B > lin.A {
+			t.Fatalf("%v: invalid premultiplied colour %v", c, lin)
+		}
+		if back := color.RGBAModel.Convert(lin).(color.RGBA); back != c {
+			t.Fatalf("expected %v, found %v", c, back)
+		}
+	}
+
+	if c := ToLinearRGBA(color.RGBA{0xbc, 0xbc, 0xbc, 0xff}); c.R < 0x7f00 || c.R > 0x8100 {
+		t.Fatalf("expected about half intensity, found %v", c)
+	}
+}
+
+func TestLinearImageRoundTrip(t *testing.T) {
+	rng := testimg.NewRNG(0)
+	img, _ := Convert(testimg.Plasma{W: 37, H: 23}.RGBA(rng))
+	lin := ToLinear(img)
+	testimg.AssertImage(t, img, lin, 0)
+	testimg.AssertImage(t, img, FromLinear(lin), 0)
+
+	set := NewLinear(img.Size)
+	for y := 0; y < img.Size.Y; y++ {
+		for x := 0; x < img.Size.X; x++ {
+			set.Set(x, y, img.At(x, y))
+		}
+	}
+	testimg.AssertImage(t, img, set, 0)
+}
+
+func TestMix(t *testing.T) {
+	black, white := color.RGBA{0, 0, 0, 0xff}, color.RGBA{0xff, 0xff, 0xff, 0xff}
+	for _, tc := range []struct {
+		a, b  color.RGBA
+		t     float64
+		light Light
+		out   color.RGBA
+	}{
+		{black, white, 0.5, GammaLight, color.RGBA{0x80, 0x80, 0x80, 0xff}},
+		{black, white, 0.5, LinearLight, color.RGBA{0xbc, 0xbc, 0xbc, 0xff}},
+		{black, white, 0, LinearLight, black},
+		{black, white, 1, LinearLight, white},
+		{color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0xff, 0, 0xff}, 0.5, LinearLight, color.RGBA{0xbc, 0xbc, 0, 0xff}},
+		{white, color.RGBA{}, 0.5, LinearLight, color.RGBA{0x80, 0x80, 0x80, 0x80}},
+	} {
+		if found := Mix(tc.a, tc.b, tc.t, tc.light); found != tc.out {
+			t.Fatalf("%v %v %f %d: expected %v, found %v", tc.a, tc.b, tc.t, tc.light, tc.out, found)
+		}
+	}
+}
+
+func TestOver(t *testing.T) {
+	dst := New(image.Pt(3, 1))
+	for i := range dst.Vals {
+		dst.Vals[i] = color.RGBA{0, 0, 0, 0xff}
+	}
+	src := New(image.Pt(3, 1))
+	for i := range src.Vals {
+		src.Vals[i] = color.RGBA{0x80, 0x80, 0x80, 0x80} // white at half opacity
+	}
+
+	gamma := dst.CloneDeep()
+	Over(gamma, src, image.Pt(1, 0), GammaLight)
+	if c := gamma.RGBAAt(1, 0); c != (color.RGBA{0x80, 0x80, 0x80, 0xff}) {
+		t.Fatal(c)
+	}
+
+	linear := dst.CloneDeep()
+	Over(linear, src, image.Pt(1, 0), LinearLight)
+	if c := linear.RGBAAt(0, 0); c != (color.RGBA{0, 0, 0, 0xff}) {
+		t.Fatal("pixel outside src was changed:", c)
+	}
+	if c := linear.RGBAAt(1, 0); c != (color.RGBA{0xbc, 0xbc, 0xbc, 0xff}) {
+		t.Fatal(c)
+	}
+
+	// Opaque and transparent sources are exact in either light:
+	src.Vals[0] = color.RGBA{0x12, 0x34, 0x56, 0xff}
+	src.Vals[1] = color.RGBA{}
+	Over(linear, src, image.Pt(0, 0), LinearLight)
+	if c := linear.RGBAAt(0, 0); c != src.Vals[0] {
+		t.Fatal(c)
+	}
+	if c := linear.RGBAAt(1, 0); c != (color.RGBA{0xbc, 0xbc, 0xbc, 0xff}) {
+		t.Fatal(c)
+	}
+}
+
+func TestResample(t *testing.T) {
+	// A fine black and white checkerboard should downscale to the grey it looks like
+	// in linear light, and something darker in gamma light:
+	src := New(image.Pt(16, 16))
+	for y := 0; y < src.Size.Y; y++ {
+		for x := 0; x < src.Size.X; x++ {
+			v := uint8(0xff * ((x + y) & 1))
+			src.Vals[y*src.Stride+x] = color.RGBA{v, v, v, 0xff}
+		}
+	}
+	for _, tc := range []struct {
+		light Light
+		grey  uint8
+	}{
+		{GammaLight, 0x80},
+		{LinearLight, 0xbc},
+	} {
+		for _, size := range []image.Point{{4, 4}, {3, 5}, {1, 1}} {
+			out := Resample(src, size, tc.light)
+			if out.Size != size {
+				t.Fatal(out.Size)
+			}
+			for _, c := range out.Vals {
+				if absDiff8(c.R, tc.grey) > 8 || c.R != c.G || c.R != c.B || c.A != 0xff {
+					t.Fatalf("light %d, size %v: expected about %#x, found %v", tc.light, size, tc.grey, c)
+				}
+			}
+		}
+	}
+
+	// Same size is a no-op, and upscaling by a whole factor gives blocks:
+	rng := testimg.NewRNG(0)
+	img, _ := Convert(testimg.WhiteNoise{W: 8, H: 8, Alpha: true}.RGBA(rng))
+	for _, light := range []Light{GammaLight, LinearLight} {
+		testimg.AssertImage(t, img, Resample(img, img.Size, light), 0)
+		up := Resample(img, image.Pt(16, 24), light)
+		for y := 0; y < up.Size.Y; y++ {
+			for x := 0; x < up.Size.X; x++ {
+				if up.RGBAAt(x, y) != img.RGBAAt(x/2, y/3) {
+					t.Fatalf("light %d: %d,%d: expected %v, found %v", light, x, y, img.RGBAAt(x/2, y/3), up.RGBAAt(x, y))
+				}
+			}
+		}
+	}
+
+	for _, size := range []image.Point{{0, 4}, {-1, 4}, {4, -1}} {
+		if out := Resample(src, size, LinearLight); len(out.Vals) != 0 {
+			t.Fatal(size, out.Size)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+	"runtime"
+	"sync"
+)
+
+// mapMinChunk is the smallest number of pixels worth handing to a goroutine. Below
+// this, the cost of starting the goroutine starts to eat the benefit.
+const mapMinChunk = 16384
+
+// mapFunc maps each colour in src to a palette index in dst. len(dst) must be
+// at least len(src).
+type mapFunc func(src []color.RGBA, dst []uint8)
+
+// serialMapper is implemented by the IndexMappers in this package to map a chunk of
+// values without spreading the work across more goroutines.
+type serialMapper interface {
+	mapVals(src []color.RGBA, dst []uint8)
+}
+
+var (
+	_ serialMapper = &rgbNode{}
+	_ serialMapper = &rgbaNode{}
+	_ serialMapper = &rgbPrecacheIndex{}
+
+	_ IndexMapper = &rgbNode{}
+	_ IndexMapper = &rgbaNode{}
+	_ IndexMapper = &rgbPrecacheIndex{}
+)
+
+// MapImage maps every pixel in src to the index of its nearest neighbour in idx,
+// writing the result into dst.Pix. dst's palette is not modified.
+//
+// If idx implements IndexMapper, that is used, otherwise a generic implementation
+// that calls idx.NearestRGBAIndex for each run of identical colours is used.
+//
+// The work is spread across up to runtime.GOMAXPROCS(0) goroutines.
+//
+// MapImage will panic if src and dst are not the same size, or if idx indexes a
+// palette with more than 256 entries, which dst.Pix can't address.
+//
+func MapImage(idx Index, src *Image, dst *image.Paletted) {
+	if mapper, ok := idx.(IndexMapper); ok {
+		mapper.MapImage(src, dst)
+		return
+	}
+	mapImageParallel(src, dst, func(src []color.RGBA, dst []uint8) {
+		mapValsIndex(idx, src, dst)
+	})
+}
+
+// MapVals maps every colour in src to the index of its nearest neighbour in idx,
+// writing the result into dst.
+//
+// See MapImage for details. MapVals will panic if len(dst) < len(src), or if idx
+// indexes a palette with more than 256 entries.
+//
+func MapVals(idx Index, src []color.RGBA, dst []uint8) {
+	if mapper, ok := idx.(IndexMapper); ok {
+		mapper.MapVals(src, dst)
+		return
+	}
+	mapValsParallel(src, dst, func(src []color.RGBA, dst []uint8) {
+		mapValsIndex(idx, src, dst)
+	})
+}
+
+// mapValsSerial maps src to dst in the current goroutine.
+func mapValsSerial(idx Index, src []color.RGBA, dst []uint8) {
+	if mapper, ok := idx.(serialMapper); ok {
+		mapper.mapVals(src, dst)
+		return
+	}
+	mapValsIndex(idx, src, dst)
+}
+
+func mapValsIndex(idx Index, src []color.RGBA, dst []uint8) {
+	if len(src) == 0 {
+		return
+	}
+	_ = dst[len(src)-1]
+
+	// Flat-colour images tend to have long runs of the same value, so caching the
+	// last result saves a lot of lookups for very little cost:
+	last := src[0]
+	lastIdx := mapIndex(idx.NearestRGBAIndex(last))
+	for i, c := range src {
+		if c != last {
+			last, lastIdx = c, mapIndex(idx.NearestRGBAIndex(c))
+		}
+		dst[i] = lastIdx
+	}
+}
+
+// mapIndex narrows a palette index to fit in an index buffer, which can only
+// address 256 entries.
+func mapIndex(idx int) uint8 {
+	if idx > 0xff {
+		panic("rgba: palette index does not fit in uint8; palette length must be <= 256")
+	}
+	return uint8(idx)
+}
+
+func mapWorkers(pixels int) int {
+	workers := runtime.GOMAXPROCS(0)
+	if max := pixels / mapMinChunk; workers > max {
+		workers = max
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+func mapValsParallel(src []color.RGBA, dst []uint8, fn mapFunc) {
+	if len(dst) < len(src) {
+		panic("rgba: len(dst) < len(src)")
+	}
+
+	workers := mapWorkers(len(src))
+	if workers == 1 {
+		fn(src, dst)
+		return
+	}
+
+	chunk := (len(src) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for start := 0; start < len(src); start += chunk {
+		end := start + chunk
+		if end > len(src) {
+			end = len(src)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			fn(src[start:end], dst[start:end])
+		}(start, end)
+	}
+	wg.Wait()
+}
+
+func mapImageParallel(src *Image, dst *image.Paletted, fn mapFunc) {
+	dstSize := dst.Rect.Size()
+	if src.Size != dstSize {
+		panic("rgba: src size did not match dst size")
+	}
+	if src.Size.X <= 0 || src.Size.Y <= 0 {
+		return
+	}
+
+	// Fast path for tightly packed images, which can be treated as one long row:
+	if src.Stride == src.Size.X && dst.Stride == dstSize.X {
+		n := src.Size.X * src.Size.Y
+		off := dst.PixOffset(dst.Rect.Min.X, dst.Rect.Min.Y)
+		mapValsParallel(src.Vals[:n], dst.Pix[off:off+n], fn)
+		return
+	}
+
+	rows := func(start, end int) {
+		for y := start; y < end; y++ {
+			soff := y * src.Stride
+			doff := dst.PixOffset(dst.Rect.Min.X, dst.Rect.Min.Y+y)
+			fn(src.Vals[soff:soff+src.Size.X], dst.Pix[doff:doff+src.Size.X])
+		}
+	}
+
+	workers := mapWorkers(src.Size.X * src.Size.Y)
+	if workers == 1 {
+		rows(0, src.Size.Y)
+		return
+	}
+
+	chunk := (src.Size.Y + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for start := 0; start < src.Size.Y; start += chunk {
+		end := start + chunk
+		if end > src.Size.Y {
+			end = src.Size.Y
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			rows(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,182 @@
+package rgba
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+// genericIndex hides any IndexMapper implementation so the fallback is exercised.
+type genericIndex struct{ Index }
+
+func TestMapImage(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pal := ConvertPalette(testimg.RandPalette(rng, 64))
+
+	indexes := []struct {
+		name string
+		idx  Index
+	}{
+		{"rgbtree", NewRGBTreeIndexer().IndexRGBAPalette(pal)},
+		{"rgbatree", NewRGBATreeIndexer().IndexRGBAPalette(pal)},
+		{"rgbprecache", NewRGBPrecacheIndexer(nil).IndexRGBAPalette(pal)},
+		{"generic", genericIndex{NewRGBATreeIndexer().IndexRGBAPalette(pal)}},
+	}
+
+	gens := []testimg.RandBlocks{
+		{W: 1, H: 1, BlockW: 1, BlockH: 1},
+		{W: 37, H: 13, BlockW: 1, BlockH: 1},
+		{W: 512, H: 512, BlockW: 4, BlockH: 4},
+	}
+
+	for _, ic := range indexes {
+		for _, gen := range gens {
+			t.Run(fmt.Sprintf("%s/%dx%d", ic.name, gen.W, gen.H), func(t *testing.T) {
+				src, _ := Convert(gen.RGBA(rng))
+
+				// Use a sub-image for dst to make sure offsets and strides are respected:
+				canvas := image.NewPaletted(image.Rect(0, 0, gen.W+3, gen.H+2), pal.ColorPalette())
+				dst := canvas.SubImage(image.Rect(2, 1, gen.W+2, gen.H+1)).(*image.Paletted)
+				MapImage(ic.idx, src, dst)
+
+				for y := 0; y < gen.H; y++ {
+					for x := 0; x < gen.W; x++ {
+						expected := ic.idx.NearestRGBAIndex(src.RGBAAt(x, y))
+						found := int(dst.ColorIndexAt(x+2, y+1))
+						if expected != found {
+							t.Fatalf("expected %d, found %d at (%d,%d)", expected, found, x, y)
+						}
+					}
+				}
+
+				vals := make([]uint8, len(src.Vals))
+				MapVals(ic.idx, src.Vals, vals)
+				for i, c := range src.Vals {
+					if expected := ic.idx.NearestRGBAIndex(c); int(vals[i]) != expected {
+						t.Fatalf("expected %d, found %d at %d", expected, vals[i], i)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestMapValsNearest(t *testing.T) {
+	rng := testimg.NewRNG(0)
+	randPal := ConvertPalette(testimg.RandPalette(rng, 64))
+	blocks, _ := Convert(testimg.RandBlocks{W: 64, H: 64, BlockW: 1, BlockH: 1}.RGBA(rng))
+
+	type mapCase struct {
+		name string
+		pal  Palette
+		src  *Image
+	}
+	var cases []mapCase
+	for _, rc := range smoothRecipes(128, 96) {
+		src, _ := Convert(rc.recipe.RGBA(rng))
+		cases = append(cases, mapCase{"smooth/" + rc.name, randPal, src})
+	}
+	for _, pc := range hardPaletteCases(rng) {
+		cases = append(cases, mapCase{"hard/" + pc.name, ConvertPalette(pc.pal), blocks})
+	}
+
+	for _, mc := range cases {
+		for _, ic := range []struct {
+			name   string
+			idx    Index
+			metric Metric
+		}{
+			{"rgbtree", NewRGBTreeIndexer().IndexRGBAPalette(mc.pal), MetricRGB},
+			{"rgbatree", NewRGBATreeIndexer().IndexRGBAPalette(mc.pal), MetricRGBA},
+			{"orchardrgb", NewOrchardIndexer(MetricRGB).IndexRGBAPalette(mc.pal), MetricRGB},
+			{"orchardrgba", NewOrchardIndexer(MetricRGBA).IndexRGBAPalette(mc.pal), MetricRGBA},
+		} {
+			t.Run(mc.name+"/"+ic.name, func(t *testing.T) {
+				vals := make([]uint8, len(mc.src.Vals))
+				MapVals(ic.idx, mc.src.Vals, vals)
+				assertNearestVals(t, mc.pal, ic.metric, mc.src.Vals, vals)
+			})
+		}
+	}
+}
+
+// assertNearestVals fails the test if any of vals is not the index of a nearest
+// neighbour in pal of the corresponding colour in src, found by brute force.
+func assertNearestVals(t *testing.T, pal Palette, metric Metric, src []color.RGBA, vals []uint8) {
+	t.Helper()
+	for i, c := range src {
+		best := metric(pal[0], c)
+		for _, pc := range pal[1:] {
+			if d := metric(pc, c); d < best {
+				best = d
+			}
+		}
+		if d := metric(pal[vals[i]], c); d != best {
+			t.Fatalf("expected distance %d, found %d for %v at %d", best, d, c, i)
+		}
+	}
+}
+
+func TestMapImageSizeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	pal := Palette{{0, 0, 0, 0xff}}
+	MapImage(pal.Index(), New(image.Pt(2, 2)), image.NewPaletted(image.Rect(0, 0, 3, 2), pal.ColorPalette()))
+}
+
+func TestMapValsPaletteTooLarge(t *testing.T) {
+	// The indexers in this package refuse palettes with more than 256 entries, but
+	// an Index from elsewhere might not:
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	src := []color.RGBA{{0, 0, 0, 0xff}, {1, 0, 0, 0xff}}
+	MapVals(largeIndex{}, src, make([]uint8, len(src)))
+}
+
+// largeIndex maps every colour to index 256 + R.
+type largeIndex struct{}
+
+func (largeIndex) NearestRGBA(c color.RGBA) (color.RGBA, int) { return c, 256 + int(c.R) }
+func (largeIndex) NearestRGBAIndex(c color.RGBA) int          { return 256 + int(c.R) }
+func (largeIndex) NearestRGBAColor(c color.RGBA) color.RGBA   { return c }
+
+func BenchmarkMapImage(b *testing.B) {
+	rng := rand.New(rand.NewSource(0))
+	pal := ConvertPalette(testimg.RandPalette(rng, 256))
+	gen := testimg.RandBlocks{W: 512, H: 512, BlockW: 8, BlockH: 8}
+	src, _ := Convert(gen.RGBA(rng))
+	dst := image.NewPaletted(src.Bounds(), pal.ColorPalette())
+
+	for _, ic := range []struct {
+		name string
+		idx  Index
+	}{
+		{"rgbtree", NewRGBTreeIndexer().IndexRGBAPalette(pal)},
+		{"rgbatree", NewRGBATreeIndexer().IndexRGBAPalette(pal)},
+		{"rgbprecache", NewRGBPrecacheIndexer(nil).IndexRGBAPalette(pal)},
+	} {
+		b.Run(ic.name+"/pixel", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for j, c := range src.Vals {
+					dst.Pix[j] = uint8(ic.idx.NearestRGBAIndex(c))
+				}
+			}
+		})
+
+		b.Run(ic.name+"/map", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				MapImage(ic.idx, src, dst)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package rgba
+
+import "image/color"
+
+// Metric measures the distance between two colours. Smaller is closer.
+//
+// Metrics are expected to behave like squared Euclidean distance: the square root
+// of the result should satisfy the triangle inequality. Indexers that prune their
+// search space (like NewOrchardIndexer) rely on this to stay accurate.
+type Metric func(a, b color.RGBA) uint32
+
+// MetricRGB is the squared Euclidean distance between the RGB components of two
+// colours. Alpha is ignored. This is the metric used by NewRGBTreeIndexer.
+func MetricRGB(a, b color.RGBA) uint32 {
+	return sqDiff8(a.R, b.R) + sqDiff8(a.G, b.G) + sqDiff8(a.B, b.B)
+}
+
+// MetricRGBA is the squared Euclidean distance between all four components of two
+// colours. This is the metric used by NewRGBATreeIndexer.
+func MetricRGBA(a, b color.RGBA) uint32 {
+	return sqDiff8(a.R, b.R) + sqDiff8(a.G, b.G) + sqDiff8(a.B, b.B) + sqDiff8(a.A, b.A)
+}
@@ -0,0 +1,330 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+)
+
+// MutableIndex is an Index which can be updated one palette entry at a time
+// without rebuilding the whole index, which suits interactive palette editors and
+// iterative quantizers (like k-means) that add, move or remove a colour at a time.
+//
+// Remove shifts all subsequent entries down by one, the same as removing an item
+// from a slice. Add returns the index of the new entry, which is always the last.
+// Add will panic if the palette already contains 256 entries.
+//
+// Palette returns the current palette. It must not be modified.
+//
+// A MutableIndex is not safe for concurrent use, though MapImage and MapVals may be
+// used with it as with any other Index.
+//
+// See NewMutableIndex.
+//
+type MutableIndex interface {
+	Index
+	Add(c color.RGBA) int
+	Replace(idx int, c color.RGBA)
+	Remove(idx int)
+	Palette() Palette
+}
+
+// MutableIndexer may be implemented by an Indexer to build a MutableIndex. The
+// palette is copied.
+//
+// See NewMutableIndex.
+//
+type MutableIndexer interface {
+	IndexRGBAPaletteMutable(pal Palette) MutableIndex
+}
+
+// NewMutableIndex builds a MutableIndex of pal using indexer. If indexer is nil,
+// DefaultIndexer is used.
+//
+// If indexer implements MutableIndexer, that is used, otherwise a generic
+// implementation is used which rebuilds the index lazily on the first lookup after
+// any change.
+//
+func NewMutableIndex(indexer Indexer, pal Palette) MutableIndex {
+	if indexer == nil {
+		indexer = DefaultIndexer
+	}
+	if mi, ok := indexer.(MutableIndexer); ok {
+		return mi.IndexRGBAPaletteMutable(pal)
+	}
+	return newLazyIndex(indexer, pal, nil, 0)
+}
+
+func (idx rgbTreeIndexer) IndexRGBAPaletteMutable(pal Palette) MutableIndex {
+	return newLazyIndex(idx, pal, MetricRGB, lazyTreeOverflow)
+}
+
+func (idx rgbaTreeIndexer) IndexRGBAPaletteMutable(pal Palette) MutableIndex {
+	return newLazyIndex(idx, pal, MetricRGBA, lazyTreeOverflow)
+}
+
+// lazyTreeOverflow is the number of added entries that will be searched linearly
+// before the tree is rebuilt.
+const lazyTreeOverflow = 16
+
+// lazyIndex wraps an Index that is rebuilt lazily. New entries are kept in an
+// overflow area which is searched linearly using metric until it grows beyond
+// maxOverflow; any other change invalidates the index until the next lookup.
+//
+// metric must match the one used by the indexer. If it is not known, maxOverflow
+// must be 0.
+type lazyIndex struct {
+	indexer     Indexer
+	metric      Metric
+	maxOverflow int
+
+	pal   Palette
+	index Index // Covers pal[:built]. nil if the index needs to be rebuilt.
+	built int
+}
+
+var _ IndexMapper = &lazyIndex{}
+
+func newLazyIndex(indexer Indexer, pal Palette, metric Metric, maxOverflow int) *lazyIndex {
+	paletteMustFit(pal)
+	li := &lazyIndex{
+		indexer:     indexer,
+		metric:      metric,
+		maxOverflow: maxOverflow,
+		pal:         append(Palette{}, pal...),
+	}
+	li.rebuild()
+	return li
+}
+
+func (li *lazyIndex) rebuild() {
+	li.built = len(li.pal)
+	if li.built == 0 {
+		li.index = nil
+		return
+	}
+	li.index = li.indexer.IndexRGBAPalette(li.pal)
+}
+
+func (li *lazyIndex) Palette() Palette { return li.pal }
+
+func (li *lazyIndex) Add(c color.RGBA) int {
+	if len(li.pal) >= 256 {
+		panic("rgba: palette length must be <= 256")
+	}
+	li.pal = append(li.pal, c)
+	return len(li.pal) - 1
+}
+
+func (li *lazyIndex) Replace(idx int, c color.RGBA) {
+	li.pal[idx] = c
+	if idx < li.built {
+		li.index = nil
+	}
+}
+
+func (li *lazyIndex) Remove(idx int) {
+	li.pal = append(li.pal[:idx], li.pal[idx+1:]...)
+	if idx < li.built {
+		li.index = nil
+	}
+}
+
+// update rebuilds the index if it is out of date. Lookups made afterwards, until
+// the next change, don't write to li.
+func (li *lazyIndex) update() {
+	if len(li.pal) > 0 && (li.index == nil || len(li.pal)-li.built > li.maxOverflow) {
+		li.rebuild()
+	}
+}
+
+func (li *lazyIndex) NearestRGBA(c color.RGBA) (nn color.RGBA, idx int) {
+	li.update()
+	if li.index == nil {
+		return nn, 0
+	}
+
+	nn, idx = li.index.NearestRGBA(c)
+	if li.built == len(li.pal) {
+		return nn, idx
+	}
+
+	best := li.metric(nn, c)
+	for i := li.built; i < len(li.pal); i++ {
+		if d := li.metric(li.pal[i], c); d < best {
+			nn, idx, best = li.pal[i], i, d
+		}
+	}
+	return nn, idx
+}
+
+func (li *lazyIndex) NearestRGBAIndex(c color.RGBA) int {
+	_, idx := li.NearestRGBA(c)
+	return idx
+}
+
+func (li *lazyIndex) NearestRGBAColor(c color.RGBA) color.RGBA {
+	nn, _ := li.NearestRGBA(c)
+	return nn
+}
+
+// MapImage brings the index up to date before the work is spread across
+// goroutines, which would otherwise race to rebuild it.
+func (li *lazyIndex) MapImage(src *Image, dst *image.Paletted) {
+	li.update()
+	if li.index != nil && li.built == len(li.pal) {
+		MapImage(li.index, src, dst)
+		return
+	}
+	mapImageParallel(src, dst, func(src []color.RGBA, dst []uint8) {
+		mapValsIndex(li, src, dst)
+	})
+}
+
+func (li *lazyIndex) MapVals(src []color.RGBA, dst []uint8) {
+	li.update()
+	if li.index != nil && li.built == len(li.pal) {
+		MapVals(li.index, src, dst)
+		return
+	}
+	mapValsParallel(src, dst, func(src []color.RGBA, dst []uint8) {
+		mapValsIndex(li, src, dst)
+	})
+}
+
+// indexerMetric returns the Metric used by one of the indexers in this package, or
+// nil if it is not known.
+func indexerMetric(indexer Indexer) Metric {
+	switch ix := indexer.(type) {
+	case rgbTreeIndexer, *rgbTreeIndexer:
+		return MetricRGB
+	case rgbaTreeIndexer, *rgbaTreeIndexer:
+		return MetricRGBA
+	case *orchardIndexer:
+		return ix.metric
+	}
+	return nil
+}
+
+// IndexRGBAPaletteMutable updates cells in place if the metric of the indexer the
+// precache is built using is known. Otherwise, the whole precache is rebuilt lazily.
+func (pc rgbPrecacheIndexer) IndexRGBAPaletteMutable(pal Palette) MutableIndex {
+	metric := indexerMetric(pc.using)
+	if metric == nil {
+		return newLazyIndex(pc, pal, nil, 0)
+	}
+
+	paletteMustFit(pal)
+	pal = append(Palette{}, pal...)
+
+	mp := &mutablePrecacheIndex{using: pc.using, metric: metric}
+	if len(pal) > 0 {
+		mp.rgbPrecacheIndex = *pc.IndexRGBAPalette(pal).(*rgbPrecacheIndex)
+	}
+	mp.pal = pal
+	return mp
+}
+
+// mutablePrecacheIndex updates only the cells affected by each change. Cells are
+// compared using the same colour the index was built with, i.e. the cell's lowest
+// corner with an alpha of 0xff.
+type mutablePrecacheIndex struct {
+	rgbPrecacheIndex
+	using  Indexer
+	metric Metric
+}
+
+func (mp *mutablePrecacheIndex) Palette() Palette { return mp.pal }
+
+func (mp *mutablePrecacheIndex) set(r, g, b int, idx int) {
+	ic := mp.pal[idx]
+	mp.index[r][g][b] = int32(idx)
+	mp.color[r][g][b] = color.RGBA{ic.R, ic.G, ic.B, 0xff}
+}
+
+// claim assigns every cell that is strictly closer to pal[idx] than its current
+// nearest neighbour to idx. Cells for which skip returns true are left alone.
+func (mp *mutablePrecacheIndex) claim(idx int, skip func(cur int32) bool) {
+	nc := mp.pal[idx]
+	for r := 0; r < 32; r++ {
+		for g := 0; g < 32; g++ {
+			for b := 0; b < 32; b++ {
+				cur := mp.index[r][g][b]
+				if skip != nil && skip(cur) {
+					continue
+				}
+				cell := color.RGBA{uint8(r << 3), uint8(g << 3), uint8(b << 3), 0xff}
+				if mp.metric(cell, nc) < mp.metric(cell, mp.pal[cur]) {
+					mp.set(r, g, b, idx)
+				}
+			}
+		}
+	}
+}
+
+// refill recomputes every cell for which match returns true from scratch.
+func (mp *mutablePrecacheIndex) refill(match func(cur int32) bool) {
+	if len(mp.pal) == 0 {
+		mp.rgbPrecacheIndex = rgbPrecacheIndex{pal: mp.pal}
+		return
+	}
+
+	var ix Index
+	for r := 0; r < 32; r++ {
+		for g := 0; g < 32; g++ {
+			for b := 0; b < 32; b++ {
+				if !match(mp.index[r][g][b]) {
+					continue
+				}
+				if ix == nil {
+					ix = mp.using.IndexRGBAPalette(mp.pal)
+				}
+				cell := color.RGBA{uint8(r << 3), uint8(g << 3), uint8(b << 3), 0xff}
+				mp.set(r, g, b, ix.NearestRGBAIndex(cell))
+			}
+		}
+	}
+}
+
+func (mp *mutablePrecacheIndex) Add(c color.RGBA) int {
+	if len(mp.pal) >= 256 {
+		panic("rgba: palette length must be <= 256")
+	}
+	mp.pal = append(mp.pal, c)
+	idx := len(mp.pal) - 1
+	if idx == 0 {
+		// The first entry is the nearest neighbour of everything:
+		mp.refill(func(int32) bool { return true })
+	} else {
+		mp.claim(idx, nil)
+	}
+	return idx
+}
+
+func (mp *mutablePrecacheIndex) Replace(idx int, c color.RGBA) {
+	mp.pal[idx] = c
+	i32 := int32(idx)
+
+	// Cells that pointed at the old colour may now be closer to something else, but
+	// all other cells only need to check whether the new colour is closer:
+	mp.refill(func(cur int32) bool { return cur == i32 })
+	mp.claim(idx, func(cur int32) bool { return cur == i32 })
+}
+
+func (mp *mutablePrecacheIndex) Remove(idx int) {
+	mp.pal = append(mp.pal[:idx], mp.pal[idx+1:]...)
+
+	const orphan = -1
+	i32 := int32(idx)
+	for r := 0; r < 32; r++ {
+		for g := 0; g < 32; g++ {
+			for b := 0; b < 32; b++ {
+				if cur := mp.index[r][g][b]; cur == i32 {
+					mp.index[r][g][b] = orphan
+				} else if cur > i32 {
+					mp.index[r][g][b] = cur - 1
+				}
+			}
+		}
+	}
+	mp.refill(func(cur int32) bool { return cur == orphan })
+}
@@ -0,0 +1,158 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+// genericIndexer hides any MutableIndexer implementation so the fallback is exercised.
+type genericIndexer struct{ Indexer }
+
+func TestMutableIndex(t *testing.T) {
+	const ops = 300
+	const queries = 100
+
+	for _, tc := range []struct {
+		name     string
+		indexer  Indexer
+		alpha    bool
+		precache bool
+	}{
+		{"rgbtree", NewRGBTreeIndexer(), false, false},
+		{"rgbatree", NewRGBATreeIndexer(), true, false},
+		{"rgbprecache", NewRGBPrecacheIndexer(nil), false, true},
+		{"rgbprecache/orchard", NewRGBPrecacheIndexer(NewOrchardIndexer(MetricRGBA)), true, true},
+		{"rgbprecache/transparent", NewRGBPrecacheIndexer(NewTransparentIndexer(NewRGBATreeIndexer(), 0, 0x10)), true, true},
+		{"generic", genericIndexer{NewRGBATreeIndexer()}, true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(0))
+			pal := ConvertPalette(testimg.RandPalette(rng, 16))
+
+			dist := func(a, b color.RGBA) uint32 {
+				d := sqDiff8(a.R, b.R) + sqDiff8(a.G, b.G) + sqDiff8(a.B, b.B)
+				if tc.alpha {
+					d += sqDiff8(a.A, b.A)
+				}
+				return d
+			}
+
+			mi := NewMutableIndex(tc.indexer, pal)
+			pal = append(Palette{}, pal...)
+
+			for op := 0; op < ops; op++ {
+				switch n := rng.Intn(3); {
+				case n == 0 && len(pal) < 256 || len(pal) <= 1:
+					c := testimg.RandRGBA(rng)
+					if idx := mi.Add(c); idx != len(pal) {
+						t.Fatal("unexpected index", idx)
+					}
+					pal = append(pal, c)
+				case n == 1:
+					idx, c := rng.Intn(len(pal)), testimg.RandRGBA(rng)
+					mi.Replace(idx, c)
+					pal[idx] = c
+				default:
+					idx := rng.Intn(len(pal))
+					mi.Remove(idx)
+					pal = append(pal[:idx], pal[idx+1:]...)
+				}
+
+				if !reflect.DeepEqual(mi.Palette(), pal) {
+					t.Fatal("palette mismatch at op", op)
+				}
+
+				fresh := tc.indexer.IndexRGBAPalette(pal)
+				for q := 0; q < queries; q++ {
+					col := testimg.RandRGBA(rng)
+					if tc.precache {
+						// Precache lookups are only as good as the cell they fall into:
+						col = color.RGBA{col.R &^ 7, col.G &^ 7, col.B &^ 7, 0xff}
+					}
+					_, idx := mi.NearestRGBA(col)
+					expected := fresh.NearestRGBAIndex(col)
+					if dist(pal[idx], col) != dist(pal[expected], col) {
+						t.Fatal("op", op, "col", col, "expected", pal[expected], "found", pal[idx])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMutableIndexMap(t *testing.T) {
+	// Large enough to spread across goroutines, which must not race to rebuild a
+	// stale index; run with -race:
+	rng := testimg.NewRNG(0)
+	src, _ := Convert(testimg.RandBlocks{W: 512, H: 512, BlockW: 1, BlockH: 1}.RGBA(rng))
+
+	for _, tc := range []struct {
+		name    string
+		indexer Indexer
+		metric  Metric
+	}{
+		{"rgbtree", NewRGBTreeIndexer(), MetricRGB},
+		{"rgbatree", NewRGBATreeIndexer(), MetricRGBA},
+		{"generic", genericIndexer{NewRGBATreeIndexer()}, MetricRGBA},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mi := NewMutableIndex(tc.indexer, ConvertPalette(testimg.RandPalette(rng, 64)))
+			for _, mutate := range []func(){
+				func() { mi.Replace(3, testimg.RandRGBA(rng)) },
+				func() { mi.Add(testimg.RandRGBA(rng)) },
+				func() { mi.Remove(0) },
+			} {
+				mutate()
+
+				dst := image.NewPaletted(image.Rect(0, 0, src.Size.X, src.Size.Y), mi.Palette().ColorPalette())
+				MapImage(mi, src, dst)
+				assertNearestVals(t, mi.Palette(), tc.metric, src.Vals, dst.Pix)
+
+				mutate()
+
+				vals := make([]uint8, len(src.Vals))
+				MapVals(mi, src.Vals, vals)
+				assertNearestVals(t, mi.Palette(), tc.metric, src.Vals, vals)
+			}
+		})
+	}
+}
+
+func BenchmarkMutableIndexReplace(b *testing.B) {
+	rng := rand.New(rand.NewSource(0))
+	pal := ConvertPalette(testimg.RandPalette(rng, 64))
+
+	cols := make([]color.RGBA, 1000)
+	for i := range cols {
+		cols[i] = testimg.RandRGBA(rng)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		indexer Indexer
+	}{
+		{"rgbtree", NewRGBTreeIndexer()},
+		{"rgbprecache", NewRGBPrecacheIndexer(nil)},
+	} {
+		b.Run(tc.name+"/mutable", func(b *testing.B) {
+			mi := NewMutableIndex(tc.indexer, pal)
+			for i := 0; i < b.N; i++ {
+				mi.Replace(i%len(pal), cols[i%len(cols)])
+				BenchSearchResult = mi.NearestRGBAIndex(cols[(i+1)%len(cols)])
+			}
+		})
+
+		b.Run(tc.name+"/rebuild", func(b *testing.B) {
+			pal := append(Palette{}, pal...)
+			for i := 0; i < b.N; i++ {
+				pal[i%len(pal)] = cols[i%len(cols)]
+				BenchSearchResult = tc.indexer.IndexRGBAPalette(pal).NearestRGBAIndex(cols[(i+1)%len(cols)])
+			}
+		})
+	}
+}
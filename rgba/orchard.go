@@ -0,0 +1,182 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// NewOrchardIndexer creates an indexer which uses Orchard's method: a matrix of the
+// distances between every pair of palette entries, and a list of each entry's
+// neighbours sorted by distance. A search walks the neighbour list of the current
+// best guess, stopping as soon as the triangle inequality proves no closer entry
+// can exist.
+//
+// For small palettes (16-64 colours or so), this can beat the tree indexers, and it
+// works with any Metric; if metric is nil, MetricRGBA is used. Memory use and build
+// time grow with the square of the palette size, so it is not a good choice for
+// large palettes. Run the benchmarks for your palette size to be sure.
+//
+// Single lookups start from a guess found in a coarse RGB grid. Batch lookups via
+// MapImage and MapVals use the previous pixel's result as the starting guess instead,
+// which is a big win for images with any spatial coherence.
+//
+// See "A Fast Nearest-Neighbor Search Algorithm", M. T. Orchard, ICASSP 1991.
+//
+func NewOrchardIndexer(metric Metric) Indexer {
+	if metric == nil {
+		metric = MetricRGBA
+	}
+	return &orchardIndexer{metric: metric}
+}
+
+type orchardIndexer struct {
+	metric Metric
+}
+
+func (oi *orchardIndexer) IndexRGBAPalette(pal Palette) Index {
+	paletteMustFit(pal)
+
+	n := len(pal)
+	ox := &orchardIndex{
+		metric: oi.metric,
+		pal:    append(Palette{}, pal...),
+		n:      n,
+		dist:   make([]uint32, n*n),
+		neigh:  make([]uint8, n*n),
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			d := oi.metric(pal[i], pal[j])
+			ox.dist[i*n+j], ox.dist[j*n+i] = d, d
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		row := ox.dist[i*n : i*n+n]
+		neigh := ox.neigh[i*n : i*n+n]
+		for j := range neigh {
+			neigh[j] = uint8(j)
+		}
+		sort.Slice(neigh, func(a, b int) bool {
+			return row[neigh[a]] < row[neigh[b]]
+		})
+	}
+
+	// Seed each coarse cell with the entry nearest its centre; the search is exact
+	// regardless of where it starts, but a close start means fewer steps:
+	if n > 0 {
+		for i := range ox.seed {
+			cell := color.RGBA{
+				R: uint8(i>>6)<<5 | 0x10,
+				G: uint8(i>>3&7)<<5 | 0x10,
+				B: uint8(i&7)<<5 | 0x10,
+				A: 0xff,
+			}
+			best, bestDist := 0, oi.metric(cell, pal[0])
+			for j := 1; j < n; j++ {
+				if d := oi.metric(cell, pal[j]); d < bestDist {
+					best, bestDist = j, d
+				}
+			}
+			ox.seed[i] = uint8(best)
+		}
+	}
+
+	return ox
+}
+
+type orchardIndex struct {
+	metric Metric
+	pal    Palette
+	n      int
+
+	// dist[i*n+j] is the distance between pal[i] and pal[j]. neigh[i*n:i*n+n] is the
+	// list of indexes into pal, sorted by their distance from pal[i]. The first
+	// entry is usually i itself (or a duplicate of it).
+	dist  []uint32
+	neigh []uint8
+
+	// seed contains a starting guess for each cell of a coarse 3-bit RGB grid.
+	seed [512]uint8
+}
+
+var (
+	_ IndexMapper  = &orchardIndex{}
+	_ serialMapper = &orchardIndex{}
+)
+
+func (ox *orchardIndex) nearest(c color.RGBA, best int) int {
+	if ox.n == 0 {
+		return 0
+	}
+
+	metric, n := ox.metric, ox.n
+	bestDist := metric(c, ox.pal[best])
+
+search:
+	for bestDist > 0 {
+		// Any entry j where dist(best, j) >= 4*dist(c, best) can't be closer than best
+		// (the 4 is because the distances are squared):
+		limit := uint64(bestDist) * 4
+		row := ox.dist[best*n : best*n+n]
+		for _, j := range ox.neigh[best*n : best*n+n] {
+			if uint64(row[j]) >= limit {
+				break search
+			}
+			if d := metric(c, ox.pal[j]); d < bestDist {
+				best, bestDist = int(j), d
+				continue search
+			}
+		}
+		break
+	}
+
+	return best
+}
+
+func (ox *orchardIndex) seedFor(c color.RGBA) int {
+	return int(ox.seed[int(c.R>>5)<<6|int(c.G>>5)<<3|int(c.B>>5)])
+}
+
+func (ox *orchardIndex) NearestRGBA(c color.RGBA) (nn color.RGBA, idx int) {
+	idx = ox.nearest(c, ox.seedFor(c))
+	if idx < len(ox.pal) {
+		nn = ox.pal[idx]
+	}
+	return nn, idx
+}
+
+func (ox *orchardIndex) NearestRGBAIndex(c color.RGBA) int {
+	return ox.nearest(c, ox.seedFor(c))
+}
+
+func (ox *orchardIndex) NearestRGBAColor(c color.RGBA) color.RGBA {
+	nn, _ := ox.NearestRGBA(c)
+	return nn
+}
+
+func (ox *orchardIndex) MapImage(src *Image, dst *image.Paletted) {
+	mapImageParallel(src, dst, ox.mapVals)
+}
+
+func (ox *orchardIndex) MapVals(src []color.RGBA, dst []uint8) {
+	mapValsParallel(src, dst, ox.mapVals)
+}
+
+func (ox *orchardIndex) mapVals(src []color.RGBA, dst []uint8) {
+	if len(src) == 0 {
+		return
+	}
+	_ = dst[len(src)-1]
+
+	last := src[0]
+	lastIdx := ox.nearest(last, ox.seedFor(last))
+	for i, c := range src {
+		if c != last {
+			last, lastIdx = c, ox.nearest(c, lastIdx)
+		}
+		dst[i] = uint8(lastIdx)
+	}
+}
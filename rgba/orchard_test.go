@@ -0,0 +1,137 @@
+package rgba
+
+import (
+	"fmt"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func TestOrchardIndexer(t *testing.T) {
+	const iter = 2000
+
+	var rng = rand.New(rand.NewSource(0))
+
+	for _, metric := range []struct {
+		name   string
+		metric Metric
+	}{
+		{"rgb", MetricRGB},
+		{"rgba", MetricRGBA},
+	} {
+		indexer := NewOrchardIndexer(metric.metric)
+
+		for _, pc := range paletteCases(rng) {
+			rpal := ConvertPalette(pc.pal)
+			idx := indexer.IndexRGBAPalette(rpal)
+
+			cols := make([]color.RGBA, iter)
+			for i := range cols {
+				cols[i] = testimg.RandRGBA(rng)
+			}
+
+			check := func(kind string, i int, found int) {
+				col := cols[i]
+				expected := 0
+				for j, c := range rpal {
+					if metric.metric(c, col) < metric.metric(rpal[expected], col) {
+						expected = j
+					}
+				}
+				dist1 := metric.metric(rpal[expected], col)
+				dist2 := metric.metric(rpal[found], col)
+				if dist1 != dist2 {
+					t.Fatal(metric.name, kind, pc.name, i, "col:", col, "expected:", rpal[expected], "found:", rpal[found],
+						"eucliddist:", dist1, "nndist:", dist2)
+				}
+			}
+
+			for i, col := range cols {
+				check("single", i, idx.NearestRGBAIndex(col))
+			}
+
+			vals := make([]uint8, len(cols))
+			MapVals(idx, cols, vals)
+			for i := range cols {
+				check("map", i, int(vals[i]))
+			}
+		}
+	}
+}
+
+var BenchOrchardResult Index
+
+func BenchmarkOrchardBuild(b *testing.B) {
+	for _, sz := range []int{16, 32, 64, 256} {
+		rng := rand.New(rand.NewSource(0))
+		pal := ConvertPalette(testimg.RandPalette(rng, sz))
+
+		b.Run(fmt.Sprintf("%d", sz), func(b *testing.B) {
+			indexer := NewOrchardIndexer(nil)
+			for i := 0; i < b.N; i++ {
+				BenchOrchardResult = indexer.IndexRGBAPalette(pal)
+			}
+		})
+	}
+}
+
+// BenchmarkIndexSearch compares the search speed of the exact indexers by palette
+// size, which is useful when choosing between them.
+func BenchmarkIndexSearch(b *testing.B) {
+	rng := rand.New(rand.NewSource(0))
+
+	colCnt := 10000
+	cols := make([]color.RGBA, colCnt)
+	for i := 0; i < colCnt; i++ {
+		cols[i] = testimg.RandRGBA(rng)
+	}
+
+	for _, sz := range []int{16, 32, 64, 256} {
+		pal := ConvertPalette(testimg.RandPalette(rng, sz))
+
+		for _, ic := range []struct {
+			name    string
+			indexer Indexer
+		}{
+			{"orchard", NewOrchardIndexer(MetricRGBA)},
+			{"rgbatree", NewRGBATreeIndexer()},
+			{"orchardrgb", NewOrchardIndexer(MetricRGB)},
+			{"rgbtree", NewRGBTreeIndexer()},
+		} {
+			idx := ic.indexer.IndexRGBAPalette(pal)
+			b.Run(fmt.Sprintf("%s/%d", ic.name, sz), func(b *testing.B) {
+				for i, j := 0, 0; i < b.N; i++ {
+					BenchSearchResult = idx.NearestRGBAIndex(cols[j])
+					j++
+					if j >= colCnt {
+						j = 0
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkIndexBuild(b *testing.B) {
+	rng := rand.New(rand.NewSource(0))
+	for _, sz := range []int{16, 32, 64, 128, 256} {
+		pal := ConvertPalette(testimg.RandPalette(rng, sz))
+		for _, ic := range []struct {
+			name    string
+			indexer Indexer
+		}{
+			{"orchard", NewOrchardIndexer(MetricRGBA)},
+			{"rgbatree", NewRGBATreeIndexer()},
+			{"orchardrgb", NewOrchardIndexer(MetricRGB)},
+			{"rgbtree", NewRGBTreeIndexer()},
+		} {
+			b.Run(fmt.Sprintf("%s/%d", ic.name, sz), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					ic.indexer.IndexRGBAPalette(pal)
+				}
+			})
+		}
+	}
+}
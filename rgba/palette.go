@@ -30,36 +30,7 @@ func ConvertPalette(pal color.Palette) (out Palette) {
 	out = make(Palette, len(pal))
 
 	for idx, c := range pal {
-		switch c := c.(type) {
-		case color.RGBA:
-			out[idx] = c
-
-		case color.RGBA64:
-			out[idx] = color.RGBA{
-				R: uint8(c.R >> 8),
-				G: uint8(c.G >> 8),
-				B: uint8(c.B >> 8),
-				A: uint8(c.A >> 8),
-			}
-
-		case color.NRGBA:
-			a32 := uint32(c.A)
-			out[idx] = color.RGBA{
-				R: uint8(uint32(c.R) * a32 / 0xff),
-				G: uint8(uint32(c.G) * a32 / 0xff),
-				B: uint8(uint32(c.B) * a32 / 0xff),
-				A: c.A,
-			}
-
-		default:
-			cr, cg, cb, ca := c.RGBA()
-			out[idx] = color.RGBA{
-				R: uint8(cr >> 8),
-				G: uint8(cg >> 8),
-				B: uint8(cb >> 8),
-				A: uint8(ca >> 8),
-			}
-		}
+		out[idx] = colorToRGBA(c)
 	}
 
 	return out
@@ -71,36 +42,8 @@ func NormalizePalette(pal color.Palette) (out color.Palette) {
 	out = make(color.Palette, len(pal))
 
 	for idx, c := range pal {
-		switch c := c.(type) {
-		case color.RGBA:
-			out[idx] = c
-
-		case color.RGBA64:
-			out[idx] = color.RGBA{
-				R: uint8(c.R >> 8),
-				G: uint8(c.G >> 8),
-				B: uint8(c.B >> 8),
-				A: uint8(c.A >> 8),
-			}
-
-		case color.NRGBA:
-			a32 := uint32(c.A)
-			out[idx] = color.RGBA{
-				R: uint8(uint32(c.R) * a32 / 0xff),
-				G: uint8(uint32(c.G) * a32 / 0xff),
-				B: uint8(uint32(c.B) * a32 / 0xff),
-				A: c.A,
-			}
-
-		default:
-			cr, cg, cb, ca := c.RGBA()
-			out[idx] = color.RGBA{
-				R: uint8(cr >> 8),
-				G: uint8(cg >> 8),
-				B: uint8(cb >> 8),
-				A: uint8(ca >> 8),
-			}
-		}
+		out[idx] = colorToRGBA(c)
 	}
+
 	return out
 }
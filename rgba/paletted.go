@@ -0,0 +1,169 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+)
+
+// Paletted is an image containing indexes into an rgba.Palette. It is the
+// indexed counterpart to rgba.Image.
+//
+// Index is used to find the nearest palette entry when calling Set or SetRGBA. It
+// may be replaced at any time if you would prefer a different Indexer, but it must
+// be built from Palette; if Palette is modified, Index should be rebuilt.
+//
+type Paletted struct {
+	Size    image.Point
+	Stride  int
+	Idx     []uint8
+	Palette Palette
+	Index   Index
+}
+
+var _ image.PalettedImage = &Paletted{}
+
+// NewPaletted creates a Paletted image using pal. If idx is nil, pal.Index() is used.
+func NewPaletted(size image.Point, pal Palette, idx Index) *Paletted {
+	if idx == nil {
+		idx = pal.Index()
+	}
+	return &Paletted{
+		Size:    size,
+		Stride:  size.X,
+		Idx:     make([]uint8, size.X*size.Y),
+		Palette: pal,
+		Index:   idx,
+	}
+}
+
+// ConvertPaletted converts an *image.Paletted into an *rgba.Paletted, using idx as
+// the Index. If idx is nil, the converted palette's Index() is used.
+//
+// The output's Idx shares img.Pix, so setting a pixel in either image changes the
+// other, and using both from different goroutines is a data race. If you require a
+// separate copy, call CloneDeep() on the output.
+//
+func ConvertPaletted(img *image.Paletted, idx Index) *Paletted {
+	pal := ConvertPalette(img.Palette)
+	if idx == nil {
+		idx = pal.Index()
+	}
+
+	size := img.Rect.Size()
+	out := &Paletted{Size: size, Stride: img.Stride, Palette: pal, Index: idx}
+	if size.X <= 0 || size.Y <= 0 {
+		out.Size, out.Stride = image.Point{}, 0
+		return out
+	}
+
+	start := img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y)
+	end := start + (size.Y-1)*img.Stride + size.X
+	out.Idx = img.Pix[start:end:end]
+	return out
+}
+
+// PalettedFromImage maps every pixel in src to its nearest neighbour in pal, using
+// idx. If idx is nil, pal.Index() is used.
+//
+// See MapImage.
+func PalettedFromImage(src *Image, pal Palette, idx Index) *Paletted {
+	out := NewPaletted(src.Size, pal, idx)
+	if src.Stride == src.Size.X {
+		MapVals(out.Index, src.Vals[:src.Size.X*src.Size.Y], out.Idx)
+		return out
+	}
+	for y := 0; y < src.Size.Y; y++ {
+		off := y * src.Stride
+		MapVals(out.Index, src.Vals[off:off+src.Size.X], out.Idx[y*out.Stride:])
+	}
+	return out
+}
+
+func (p *Paletted) CloneDeep() *Paletted {
+	idx := make([]uint8, len(p.Idx))
+	copy(idx, p.Idx)
+	pal := make(Palette, len(p.Palette))
+	copy(pal, p.Palette)
+	return &Paletted{Size: p.Size, Stride: p.Stride, Idx: idx, Palette: pal, Index: p.Index}
+}
+
+// ImagePaletted returns the rgba.Paletted as an *image.Paletted. The returned image
+// shares Idx with p.
+func (p *Paletted) ImagePaletted() *image.Paletted {
+	return &image.Paletted{
+		Pix:     p.Idx,
+		Stride:  p.Stride,
+		Rect:    image.Rectangle{Max: p.Size},
+		Palette: p.Palette.ColorPalette(),
+	}
+}
+
+// Image returns a copy of the rgba.Paletted as an rgba.Image.
+func (p *Paletted) Image() *Image {
+	out := New(p.Size)
+	for y := 0; y < p.Size.Y; y++ {
+		in := p.Idx[y*p.Stride : y*p.Stride+p.Size.X]
+		vals := out.Vals[y*out.Stride:]
+		for x, i := range in {
+			if int(i) < len(p.Palette) {
+				vals[x] = p.Palette[i]
+			}
+		}
+	}
+	return out
+}
+
+func (p *Paletted) ColorModel() color.Model {
+	return p.Palette.ColorPalette()
+}
+
+func (p *Paletted) Bounds() image.Rectangle {
+	return image.Rectangle{Max: p.Size}
+}
+
+func (p *Paletted) At(x, y int) color.Color {
+	return p.RGBAAt(x, y)
+}
+
+func (p *Paletted) PixOffset(x, y int) int {
+	return y*p.Stride + x
+}
+
+func (p *Paletted) RGBAAt(x, y int) (c color.RGBA) {
+	if x >= p.Size.X || y >= p.Size.Y {
+		return c
+	}
+	i := int(p.Idx[y*p.Stride+x])
+	if i >= len(p.Palette) {
+		return c
+	}
+	return p.Palette[i]
+}
+
+func (p *Paletted) ColorIndexAt(x, y int) uint8 {
+	if x >= p.Size.X || y >= p.Size.Y {
+		return 0
+	}
+	return p.Idx[y*p.Stride+x]
+}
+
+func (p *Paletted) SetColorIndex(x, y int, index uint8) {
+	if x >= p.Size.X || y >= p.Size.Y {
+		return
+	}
+	p.Idx[y*p.Stride+x] = index
+}
+
+func (p *Paletted) Set(x, y int, c color.Color) {
+	if x >= p.Size.X || y >= p.Size.Y {
+		return
+	}
+	p.Idx[y*p.Stride+x] = uint8(p.Index.NearestRGBAIndex(colorToRGBA(c)))
+}
+
+func (p *Paletted) SetRGBA(x, y int, c color.RGBA) {
+	if x >= p.Size.X || y >= p.Size.Y {
+		return
+	}
+	p.Idx[y*p.Stride+x] = uint8(p.Index.NearestRGBAIndex(c))
+}
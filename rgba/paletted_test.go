@@ -0,0 +1,69 @@
+package rgba
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func TestConvertPaletted(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	gen := testimg.RandBlocks{W: 64, H: 48, BlockW: 3, BlockH: 3}
+
+	full := gen.Paletted(rng, testimg.RandPalette(rng, 32))
+	sub := full.SubImage(image.Rect(5, 7, 40, 30)).(*image.Paletted)
+
+	for _, src := range []*image.Paletted{full, sub} {
+		pimg := ConvertPaletted(src, nil)
+		if pimg.Size != src.Rect.Size() {
+			t.Fatal(pimg.Size, "!=", src.Rect.Size())
+		}
+
+		back := pimg.ImagePaletted()
+		rimg, copied := Convert(pimg)
+		if !copied {
+			t.Fatal()
+		}
+
+		for y := 0; y < pimg.Size.Y; y++ {
+			for x := 0; x < pimg.Size.X; x++ {
+				sx, sy := x+src.Rect.Min.X, y+src.Rect.Min.Y
+				if ex, fd := src.ColorIndexAt(sx, sy), pimg.ColorIndexAt(x, y); ex != fd {
+					t.Fatalf("index %d != %d at (%d,%d)", ex, fd, x, y)
+				}
+				if ex, fd := src.ColorIndexAt(sx, sy), back.ColorIndexAt(x, y); ex != fd {
+					t.Fatalf("back index %d != %d at (%d,%d)", ex, fd, x, y)
+				}
+				if ex, fd := colorToRGBA(src.At(sx, sy)), rimg.RGBAAt(x, y); ex != fd {
+					t.Fatalf("color %v != %v at (%d,%d)", ex, fd, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestPalettedFromImage(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pal := ConvertPalette(testimg.RandPalette(rng, 64))
+	gen := testimg.RandBlocks{W: 100, H: 80, BlockW: 2, BlockH: 2}
+	src, _ := Convert(gen.RGBA(rng))
+
+	idx := NewRGBTreeIndexer().IndexRGBAPalette(pal)
+	pimg := PalettedFromImage(src, pal, idx)
+
+	for y := 0; y < src.Size.Y; y++ {
+		for x := 0; x < src.Size.X; x++ {
+			expected := idx.NearestRGBAIndex(src.RGBAAt(x, y))
+			if found := int(pimg.ColorIndexAt(x, y)); expected != found {
+				t.Fatalf("expected %d, found %d at (%d,%d)", expected, found, x, y)
+			}
+		}
+	}
+
+	pimg.Set(0, 0, pal[7])
+	if pimg.ColorIndexAt(0, 0) != 7 {
+		t.Fatal()
+	}
+}
@@ -0,0 +1,307 @@
+package rgba
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+)
+
+// The palette manipulation functions in this file all return a new Palette rather
+// than modifying the input, along with a translation table that maps each index in
+// the original palette to its index in the new one. The table can be passed to
+// ApplyRemapTable or Paletted.ApplyRemapTable to rewrite an image to match:
+//
+//	pal, table := rgba.ConvertPalette(img.Palette).Dedupe()
+//	rgba.ApplyRemapTable(img, pal, table)
+//
+// Palettes must contain 256 entries or fewer.
+
+// PaletteSort selects the ordering used by Palette.Sort.
+type PaletteSort int
+
+const (
+	// PaletteSortLuminance orders entries from darkest to lightest, using Rec. 709
+	// luma coefficients on the gamma-encoded values.
+	PaletteSortLuminance PaletteSort = iota
+
+	// PaletteSortHue orders entries by HSV hue, starting from red. Entries with no
+	// saturation come first, ordered by luminance.
+	PaletteSortHue
+
+	// PaletteSortHilbert orders entries by their position along a Hilbert curve
+	// through the RGB cube, which tends to keep similar colours next to each other.
+	PaletteSortHilbert
+)
+
+// Dedupe removes all but the first instance of each colour in the palette.
+func (p Palette) Dedupe() (out Palette, table []uint8) {
+	paletteMustFit(p)
+
+	table = make([]uint8, len(p))
+	seen := make(map[color.RGBA]uint8, len(p))
+	out = make(Palette, 0, len(p))
+
+	for i, c := range p {
+		if at, ok := seen[c]; ok {
+			table[i] = at
+			continue
+		}
+		at := uint8(len(out))
+		seen[c] = at
+		table[i] = at
+		out = append(out, c)
+	}
+	return out, table
+}
+
+// RemoveUnused removes all entries that are not referenced by any pixel in img.
+// Entries in the table that refer to removed entries are mapped to 0.
+func (p Palette) RemoveUnused(img *image.Paletted) (out Palette, table []uint8) {
+	paletteMustFit(p)
+
+	var used [256]bool
+	size := img.Rect.Size()
+	for y := 0; y < size.Y; y++ {
+		off := img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y)
+		for _, v := range img.Pix[off : off+size.X] {
+			used[v] = true
+		}
+	}
+
+	table = make([]uint8, len(p))
+	out = make(Palette, 0, len(p))
+	for i, c := range p {
+		if used[i] {
+			table[i] = uint8(len(out))
+			out = append(out, c)
+		}
+	}
+	return out, table
+}
+
+// Sort returns a copy of the palette ordered by the key. Sorting is stable; entries
+// that compare equal retain their original order.
+func (p Palette) Sort(by PaletteSort) (out Palette, table []uint8) {
+	paletteMustFit(p)
+
+	keys := make([]uint64, len(p))
+	for i, c := range p {
+		switch by {
+		case PaletteSortLuminance:
+			keys[i] = uint64(luma709(c))
+		case PaletteSortHue:
+			keys[i] = hueKey(c)
+		case PaletteSortHilbert:
+			keys[i] = uint64(hilbertRGB(c))
+		default:
+			panic(fmt.Errorf("rgba: unknown PaletteSort %d", by))
+		}
+	}
+
+	perm := make([]int, len(p))
+	for i := range perm {
+		perm[i] = i
+	}
+	sort.SliceStable(perm, func(i, j int) bool {
+		return keys[perm[i]] < keys[perm[j]]
+	})
+
+	return p.Reorder(perm)
+}
+
+// Reorder returns a copy of the palette where out[i] == p[perm[i]]. perm must be a
+// permutation of the indexes of p.
+func (p Palette) Reorder(perm []int) (out Palette, table []uint8) {
+	paletteMustFit(p)
+	if len(perm) != len(p) {
+		panic("rgba: len(perm) did not match len(palette)")
+	}
+
+	var seen [256]bool
+	out = make(Palette, len(p))
+	table = make([]uint8, len(p))
+	for i, from := range perm {
+		if from < 0 || from >= len(p) || seen[from] {
+			panic(fmt.Errorf("rgba: invalid permutation at index %d", i))
+		}
+		seen[from] = true
+		out[i] = p[from]
+		table[from] = uint8(i)
+	}
+	return out, table
+}
+
+// Merge appends each colour in 'other' that is not already present in p. The
+// indexes of p are preserved; the table maps indexes of 'other' into the result.
+func (p Palette) Merge(other Palette) (out Palette, otherTable []uint8, err error) {
+	paletteMustFit(p)
+	paletteMustFit(other)
+
+	at := make(map[color.RGBA]int, len(p)+len(other))
+	out = make(Palette, len(p), len(p)+len(other))
+	copy(out, p)
+	for i := len(p) - 1; i >= 0; i-- {
+		at[p[i]] = i
+	}
+
+	otherTable = make([]uint8, len(other))
+	for i, c := range other {
+		idx, ok := at[c]
+		if !ok {
+			idx = len(out)
+			if idx >= 256 {
+				return nil, nil, fmt.Errorf("rgba: merged palette has more than 256 entries")
+			}
+			at[c] = idx
+			out = append(out, c)
+		}
+		otherTable[i] = uint8(idx)
+	}
+	return out, otherTable, nil
+}
+
+// PinTransparent ensures there is a fully transparent entry at index 'at'. If the
+// palette already contains a fully transparent entry, the first one is moved to 'at',
+// otherwise a new one is inserted there.
+//
+// This is useful when writing GIFs or tilesets that expect the transparent entry at a
+// known position.
+func (p Palette) PinTransparent(at int) (out Palette, table []uint8, err error) {
+	paletteMustFit(p)
+
+	trans := -1
+	for i, c := range p {
+		if c.A == 0 {
+			trans = i
+			break
+		}
+	}
+
+	if trans < 0 {
+		if at < 0 || at > len(p) {
+			return nil, nil, fmt.Errorf("rgba: transparent index %d out of range", at)
+		}
+		if len(p) >= 256 {
+			return nil, nil, fmt.Errorf("rgba: palette is full, can't insert transparent entry")
+		}
+		out = make(Palette, 0, len(p)+1)
+		out = append(out, p[:at]...)
+		out = append(out, color.RGBA{})
+		out = append(out, p[at:]...)
+
+		table = make([]uint8, len(p))
+		for i := range p {
+			if i < at {
+				table[i] = uint8(i)
+			} else {
+				table[i] = uint8(i + 1)
+			}
+		}
+		return out, table, nil
+	}
+
+	if at < 0 || at >= len(p) {
+		return nil, nil, fmt.Errorf("rgba: transparent index %d out of range", at)
+	}
+
+	// Move the entry, shifting everything in between:
+	perm := make([]int, 0, len(p))
+	for i := range p {
+		if i != trans {
+			perm = append(perm, i)
+		}
+	}
+	perm = append(perm[:at], append([]int{trans}, perm[at:]...)...)
+
+	out, table = p.Reorder(perm)
+	return out, table, nil
+}
+
+// ApplyRemapTable rewrites img to use the palette 'to', translating each pixel
+// using 'table'. Pixels outside the range of the table are mapped to 0.
+//
+// See RemapTable and the palette manipulation functions on Palette, which all
+// return a table suitable for use here.
+func ApplyRemapTable(img *image.Paletted, to Palette, table []uint8) {
+	start := img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y)
+	applyRemapTable(img.Pix, img.Stride, img.Rect.Size(), start, table)
+	img.Palette = to.ColorPalette()
+}
+
+// ApplyRemapTable rewrites p to use the palette 'to', translating each pixel
+// using 'table'. p.Index is rebuilt using the default indexer.
+//
+// See the package-level ApplyRemapTable for more details.
+func (p *Paletted) ApplyRemapTable(to Palette, table []uint8) {
+	applyRemapTable(p.Idx, p.Stride, p.Size, 0, table)
+	p.Palette, p.Index = to, to.Index()
+}
+
+func applyRemapTable(pix []uint8, stride int, size image.Point, start int, table []uint8) {
+	if size.X <= 0 || size.Y <= 0 {
+		return
+	}
+	// Use a full-sized table so out-of-range pixels don't need a bounds check:
+	var full [256]uint8
+	copy(full[:], table)
+	for y := 0; y < size.Y; y++ {
+		row := pix[start+y*stride : start+y*stride+size.X]
+		for x, v := range row {
+			row[x] = full[v]
+		}
+	}
+}
+
+func paletteMustFit(p Palette) {
+	if len(p) > 256 {
+		// FIXME: would be good to remove this limitation
+		panic(fmt.Errorf("palette length must be <= 256"))
+	}
+}
+
+func luma709(c color.RGBA) uint32 {
+	// Coefficients scaled by 10000 to stay in integer space:
+	return 2126*uint32(c.R) + 7152*uint32(c.G) + 722*uint32(c.B)
+}
+
+// hueKey packs HSV hue above luminance so that greys (which have no hue) sort before
+// everything else, and colours with the same hue sort by luminance. The hue needs 15
+// bits above the 18 of luminance, so the key doesn't fit in a uint32.
+func hueKey(c color.RGBA) uint64 {
+	r, g, b := int32(c.R), int32(c.G), int32(c.B)
+	max, min := r, r
+	if g > max {
+		max = g
+	}
+	if b > max {
+		max = b
+	}
+	if g < min {
+		min = g
+	}
+	if b < min {
+		min = b
+	}
+
+	lum := uint64(luma709(c) / 10) // fits in 18 bits
+	delta := max - min
+	if delta == 0 {
+		return lum
+	}
+
+	// Hue in 1/64ths of a degree, offset by 1 to stay clear of the greys:
+	var hue int32
+	switch max {
+	case r:
+		hue = 60 * 64 * (g - b) / delta
+	case g:
+		hue = 60*64*(b-r)/delta + 120*64
+	default:
+		hue = 60*64*(r-g)/delta + 240*64
+	}
+	if hue < 0 {
+		hue += 360 * 64
+	}
+	return uint64(hue+1)<<18 | lum
+}
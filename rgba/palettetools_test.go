@@ -0,0 +1,209 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+// assertRemapPreserves checks that rewriting img from 'from' to 'to' using table does not
+// change the colour of any pixel.
+func assertRemapPreserves(t *testing.T, img *image.Paletted, to Palette, table []uint8) {
+	t.Helper()
+
+	before, _ := Convert(img)
+	before = before.CloneDeep()
+	ApplyRemapTable(img, to, table)
+	after, _ := Convert(img)
+
+	for i := range before.Vals {
+		if before.Vals[i] != after.Vals[i] {
+			t.Fatalf("colour changed at %d: %v != %v", i, before.Vals[i], after.Vals[i])
+		}
+	}
+}
+
+func TestPaletteDedupe(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	base := ConvertPalette(testimg.RandPalette(rng, 16))
+	pal := append(append(Palette{}, base...), base[3], base[0], base[15])
+
+	out, table := pal.Dedupe()
+	if len(out) != 16 {
+		t.Fatal(len(out))
+	}
+	for i, c := range pal {
+		if out[table[i]] != c {
+			t.Fatal(i)
+		}
+	}
+
+	gen := testimg.RandBlocks{W: 32, H: 32, BlockW: 2, BlockH: 2}
+	assertRemapPreserves(t, gen.Paletted(rng, pal.ColorPalette()), out, table)
+}
+
+func TestPaletteRemoveUnused(t *testing.T) {
+	pal := ConvertPalette(palette.WebSafe[:8])
+	img := image.NewPaletted(image.Rect(0, 0, 4, 1), pal.ColorPalette())
+	copy(img.Pix, []uint8{1, 5, 5, 6})
+
+	out, table := pal.RemoveUnused(img)
+	if len(out) != 3 {
+		t.Fatal(len(out))
+	}
+	if out[0] != pal[1] || out[1] != pal[5] || out[2] != pal[6] {
+		t.Fatal(out)
+	}
+	assertRemapPreserves(t, img, out, table)
+}
+
+func TestPaletteSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pal := ConvertPalette(palette.Plan9)
+	gen := testimg.RandBlocks{W: 32, H: 32, BlockW: 1, BlockH: 1}
+
+	for _, by := range []PaletteSort{PaletteSortLuminance, PaletteSortHue, PaletteSortHilbert} {
+		out, table := pal.Sort(by)
+		if len(out) != len(pal) {
+			t.Fatal(by)
+		}
+		for i := 1; i < len(out); i++ {
+			var a, b uint32
+			switch by {
+			case PaletteSortLuminance:
+				a, b = luma709(out[i-1]), luma709(out[i])
+			case PaletteSortHilbert:
+				a, b = hilbertRGB(out[i-1]), hilbertRGB(out[i])
+			}
+			if a > b {
+				t.Fatal(by, "not sorted at", i)
+			}
+		}
+		assertRemapPreserves(t, gen.Paletted(rng, pal.ColorPalette()), out, table)
+	}
+
+	// Greys by luminance, then colours by hue, all the way round to 360:
+	hues := Palette{
+		{0x00, 0x00, 0x00, 0xff},
+		{0x80, 0x80, 0x80, 0xff},
+		{0xff, 0xff, 0xff, 0xff},
+		{0x80, 0x00, 0x00, 0xff}, // 0, dark
+		{0xff, 0x00, 0x00, 0xff}, // 0
+		{0xff, 0x80, 0x00, 0xff}, // 30
+		{0xff, 0xff, 0x00, 0xff}, // 60
+		{0x00, 0xff, 0x00, 0xff}, // 120
+		{0x00, 0xff, 0xff, 0xff}, // 180
+		{0x00, 0x00, 0xff, 0xff}, // 240
+		{0x80, 0x00, 0xff, 0xff}, // 270
+		{0xff, 0x00, 0xc8, 0xff}, // 313
+		{0xff, 0x00, 0x0a, 0xff}, // 358
+	}
+	perm := rng.Perm(len(hues))
+	shuffled, _ := hues.Reorder(perm)
+	if out, _ := shuffled.Sort(PaletteSortHue); !reflect.DeepEqual(out, hues) {
+		t.Fatalf("expected %v, found %v", hues, out)
+	}
+}
+
+func TestPaletteMerge(t *testing.T) {
+	a := ConvertPalette(palette.WebSafe[:10])
+	b := ConvertPalette(palette.WebSafe[5:20])
+
+	out, table, err := a.Merge(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 20 {
+		t.Fatal(len(out))
+	}
+	for i, c := range b {
+		if out[table[i]] != c {
+			t.Fatal(i)
+		}
+	}
+
+	if _, _, err := ConvertPalette(palette.WebSafe).Merge(ConvertPalette(palette.Plan9)); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Exactly 256 entries after merging is fine, 257 is not:
+	extra := make(Palette, 41)
+	for i := range extra {
+		extra[i] = color.RGBA{uint8(i), 0, 1, 0xff}
+	}
+	websafe := ConvertPalette(palette.WebSafe)
+	if out, _, err := websafe.Merge(extra[:40]); err != nil || len(out) != 256 {
+		t.Fatal(len(out), err)
+	}
+	if _, _, err := websafe.Merge(extra); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestPalettePinTransparent(t *testing.T) {
+	pal := ConvertPalette(palette.WebSafe[:8])
+
+	out, table, err := pal.PinTransparent(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 9 || out[0] != (color.RGBA{}) {
+		t.Fatal(out)
+	}
+	img := image.NewPaletted(image.Rect(0, 0, 8, 1), pal.ColorPalette())
+	copy(img.Pix, []uint8{0, 1, 2, 3, 4, 5, 6, 7})
+	assertRemapPreserves(t, img, out, table)
+
+	// Move existing entry:
+	pal = append(pal, color.RGBA{})
+	out, table, err = pal.PinTransparent(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 9 || out[2] != (color.RGBA{}) || table[8] != 2 {
+		t.Fatal(out, table)
+	}
+	img = image.NewPaletted(image.Rect(0, 0, 9, 1), pal.ColorPalette())
+	copy(img.Pix, []uint8{0, 1, 2, 3, 4, 5, 6, 7, 8})
+	assertRemapPreserves(t, img, out, table)
+}
+
+func TestHilbertRGB(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	// Every point in the cube must be visited exactly once, and each step along the
+	// curve must move to an adjacent point:
+	const n = 1 << 24
+	points := make([]uint32, n)
+	seen := make([]bool, n)
+	for i := 0; i < n; i++ {
+		h := hilbertRGB(color.RGBA{R: uint8(i >> 16), G: uint8(i >> 8), B: uint8(i)})
+		if seen[h] {
+			t.Fatal("duplicate", h)
+		}
+		seen[h] = true
+		points[h] = uint32(i)
+	}
+
+	for i := 1; i < n; i++ {
+		a, b := points[i-1], points[i]
+		var dist int
+		for _, shift := range []uint{16, 8, 0} {
+			d := int((a>>shift)&0xff) - int((b>>shift)&0xff)
+			if d < 0 {
+				d = -d
+			}
+			dist += d
+		}
+		if dist != 1 {
+			t.Fatalf("step %d is not adjacent: %06x -> %06x", i, a, b)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// QuantizationError describes how well an Index represents an image. See
+// MeasureQuantization.
+type QuantizationError struct {
+	Pixels int
+
+	// MeanError and MaxError are the mean and maximum Euclidean distance between
+	// each pixel and its nearest neighbour, across all four channels. The largest
+	// possible distance is 510.
+	MeanError float64
+	MaxError  float64
+
+	// MSE is the mean squared error per channel, across all four channels. PSNR is
+	// the peak signal-to-noise ratio in dB derived from MSE; it is +Inf if the image
+	// is represented exactly.
+	MSE  float64
+	PSNR float64
+
+	// MeanDeltaE and MaxDeltaE are the mean and maximum CIEDE2000 colour difference
+	// between each pixel and its nearest neighbour. As a rough guide, a ΔE of 1 is
+	// about the smallest difference most people can see. Alpha is ignored, and
+	// premultiplied colours are treated as if composited over black.
+	MeanDeltaE float64
+	MaxDeltaE  float64
+}
+
+// MeasureQuantization maps every pixel in img to its nearest neighbour in idx and
+// reports on the error that would introduce.
+//
+// If heatmap is true, a per-pixel heat-map of the error is also returned, where the
+// brightness of each pixel is proportional to its Euclidean error, scaled so the
+// largest error in the image is white. Otherwise, heat is nil.
+//
+// This is also useful to compare an approximate Index (like NewRGBPrecacheIndexer)
+// against an exact one.
+//
+func MeasureQuantization(img *Image, idx Index, heatmap bool) (qe QuantizationError, heat *Image) {
+	size := img.Size
+	qe.Pixels = size.X * size.Y
+	if qe.Pixels <= 0 {
+		if heatmap {
+			heat = New(image.Point{})
+		}
+		return qe, heat
+	}
+
+	var errs []float64
+	if heatmap {
+		errs = make([]float64, qe.Pixels)
+	}
+
+	var (
+		sumErr, sumSq, sumDE float64
+		last                 color.RGBA
+		lastDist, lastDE     float64
+		lastSq               uint32
+		first                = true
+	)
+
+	for y, i := 0, 0; y < size.Y; y++ {
+		for _, c := range img.Vals[y*img.Stride : y*img.Stride+size.X] {
+			// Lookups and ΔE are expensive, so cache the last result for runs of the
+			// same colour:
+			if first || c != last {
+				nn := idx.NearestRGBAColor(c)
+				lastSq = MetricRGBA(c, nn)
+				lastDist = math.Sqrt(float64(lastSq))
+				l1, a1, b1 := labFromRGBA(c)
+				l2, a2, b2 := labFromRGBA(nn)
+				lastDE = deltaE2000(l1, a1, b1, l2, a2, b2)
+				last, first = c, false
+			}
+
+			sumErr += lastDist
+			sumSq += float64(lastSq)
+			sumDE += lastDE
+			if lastDist > qe.MaxError {
+				qe.MaxError = lastDist
+			}
+			if lastDE > qe.MaxDeltaE {
+				qe.MaxDeltaE = lastDE
+			}
+			if heatmap {
+				errs[i] = lastDist
+			}
+			i++
+		}
+	}
+
+	n := float64(qe.Pixels)
+	qe.MeanError = sumErr / n
+	qe.MeanDeltaE = sumDE / n
+	qe.MSE = sumSq / (n * 4)
+	if qe.MSE == 0 {
+		qe.PSNR = math.Inf(1)
+	} else {
+		qe.PSNR = 10 * math.Log10(255*255/qe.MSE)
+	}
+
+	if heatmap {
+		heat = New(size)
+		for i, e := range errs {
+			var v uint8
+			if qe.MaxError > 0 {
+				v = uint8(math.Round(e * 255 / qe.MaxError))
+			}
+			heat.Vals[i] = color.RGBA{v, v, v, 0xff}
+		}
+	}
+
+	return qe, heat
+}
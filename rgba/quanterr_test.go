@@ -0,0 +1,80 @@
+package rgba
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func TestDeltaE2000(t *testing.T) {
+	// A selection of the test data from Sharma, Wu and Dalal (2005):
+	for idx, tc := range []struct {
+		l1, a1, b1, l2, a2, b2 float64
+		de                     float64
+	}{
+		{50.0000, 2.6772, -79.7751, 50.0000, 0.0000, -82.7485, 2.0425},
+		{50.0000, -1.3802, -84.2814, 50.0000, 0.0000, -82.7485, 1.0000},
+		{50.0000, 0.0000, 0.0000, 50.0000, -1.0000, 2.0000, 2.3669},
+		{50.0000, 2.4900, -0.0010, 50.0000, -2.4900, 0.0011, 7.2195},
+		{50.0000, 2.5000, 0.0000, 73.0000, 25.0000, -18.0000, 27.1492},
+		{60.2574, -34.0099, 36.2677, 60.4626, -34.1751, 39.4387, 1.2644},
+		{22.7233, 20.0904, -46.6940, 23.0331, 14.9730, -42.5619, 2.0373},
+		{90.9257, -0.5406, -0.9208, 88.6381, -0.8985, -0.7239, 1.5381},
+		{2.0776, 0.0795, -1.1350, 0.9033, -0.0636, -0.5514, 0.9082},
+	} {
+		de := deltaE2000(tc.l1, tc.a1, tc.b1, tc.l2, tc.a2, tc.b2)
+		if math.Abs(de-tc.de) > 0.0001 {
+			t.Fatal(idx, "expected", tc.de, "found", de)
+		}
+	}
+}
+
+func TestMeasureQuantization(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	gen := testimg.RandBlocks{W: 128, H: 128, BlockW: 2, BlockH: 2}
+	img, _ := Convert(gen.RGBA(rng))
+	pal := ConvertPalette(testimg.RandPalette(rng, 64))
+
+	exact, heat := MeasureQuantization(img, NewRGBTreeIndexer().IndexRGBAPalette(pal), true)
+	approx, _ := MeasureQuantization(img, NewRGBPrecacheIndexer(nil).IndexRGBAPalette(pal), false)
+
+	if exact.Pixels != 128*128 || heat.Size != img.Size {
+		t.Fatal()
+	}
+	if exact.MeanError <= 0 || exact.MaxError < exact.MeanError || exact.MaxDeltaE < exact.MeanDeltaE {
+		t.Fatal(exact)
+	}
+	if approx.MeanError < exact.MeanError || approx.MSE < exact.MSE || approx.PSNR > exact.PSNR {
+		t.Fatal("approximate index beat exact index", approx, exact)
+	}
+
+	var white bool
+	for _, c := range heat.Vals {
+		white = white || c.R == 0xff
+	}
+	if !white {
+		t.Fatal("heat-map should contain the maximum error")
+	}
+
+	// An image made entirely of palette entries has no error:
+	pimg := NewPaletted(img.Size, pal, nil)
+	for i := range pimg.Idx {
+		pimg.Idx[i] = uint8(rng.Intn(len(pal)))
+	}
+	none, _ := MeasureQuantization(pimg.Image(), pal.Index(), false)
+	if none.MaxError != 0 || none.MaxDeltaE != 0 || !math.IsInf(none.PSNR, 1) {
+		t.Fatal(none)
+	}
+
+	// Empty images only get a heat map if one was asked for:
+	empty := New(image.Point{})
+	if _, heat := MeasureQuantization(empty, pal.Index(), false); heat != nil {
+		t.Fatal("expected nil heat map")
+	}
+	if _, heat := MeasureQuantization(empty, pal.Index(), true); heat == nil || len(heat.Vals) != 0 {
+		t.Fatal("expected empty heat map, found", heat)
+	}
+}
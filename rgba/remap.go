@@ -0,0 +1,181 @@
+package rgba
+
+import (
+	"image"
+)
+
+// RemapDither selects how a remap handles source palette entries that do not have
+// an exact match in the destination palette.
+type RemapDither int
+
+const (
+	// RemapNearest maps each source entry to its nearest destination entry.
+	RemapNearest RemapDither = iota
+
+	// RemapOrdered4x4 maps each source entry to a mix of two destination entries,
+	// arranged using a 4x4 Bayer matrix. The first entry is the nearest neighbour of
+	// the source colour, the second is the nearest neighbour of the source colour
+	// reflected away from the first, and the proportion of each is found by projecting
+	// the source colour onto the line between them.
+	//
+	// Source entries with an exact match are never dithered.
+	RemapOrdered4x4
+
+	// RemapOrderedLinear4x4 is like RemapOrdered4x4, but finds the second entry and
+	// the proportion of each in linear light, so the dithered area averages out to
+	// the source colour's brightness rather than something darker.
+	RemapOrderedLinear4x4
+)
+
+var bayer4x4 = [16]uint8{
+	0, 8, 2, 10,
+	12, 4, 14, 6,
+	3, 11, 1, 9,
+	15, 7, 13, 5,
+}
+
+// RemapTable builds a translation table from each entry in 'from' to the index of
+// its nearest neighbour in 'to', which must be an Index of the destination palette.
+// RemapTable will panic if 'to' returns an index that doesn't fit in a uint8.
+//
+// The returned table can be used to translate an index buffer directly:
+//
+//	table := rgba.RemapTable(from, to)
+//	for i, v := range img.Pix {
+//		img.Pix[i] = table[v]
+//	}
+//
+func RemapTable(from Palette, to Index) []uint8 {
+	out := make([]uint8, len(from))
+	for i, c := range from {
+		out[i] = mapIndex(to.NearestRGBAIndex(c))
+	}
+	return out
+}
+
+// RemapOrderedTable builds a translation table for RemapOrdered4x4. Each entry
+// contains the destination index to use for each cell of the 4x4 matrix, which is
+// found at table[src][(y&3)<<2|(x&3)].
+//
+// 'to' must be an Index of the destination palette.
+//
+func RemapOrderedTable(from Palette, to Index) [][16]uint8 {
+	return remapOrderedTable(from, to, GammaLight)
+}
+
+// RemapOrderedLinearTable builds a translation table for RemapOrderedLinear4x4,
+// laid out like RemapOrderedTable.
+func RemapOrderedLinearTable(from Palette, to Index) [][16]uint8 {
+	return remapOrderedTable(from, to, LinearLight)
+}
+
+func remapOrderedTable(from Palette, to Index, light Light) [][16]uint8 {
+	out := make([][16]uint8, len(from))
+
+	for i, c := range from {
+		n1c, n1 := to.NearestRGBA(c)
+		var cells = &out[i]
+		for j := range cells {
+			cells[j] = mapIndex(n1)
+		}
+		if n1c == c {
+			continue
+		}
+
+		// Reflect the source colour away from its nearest neighbour to find a
+		// candidate on the "other side":
+		cv, n1v := lightVals(c, light), lightVals(n1c, light)
+		var refl [4]float32
+		for k := range refl {
+			refl[k] = 2*cv[k] - n1v[k]
+		}
+		n2c, n2 := to.NearestRGBA(lightColor(refl, light))
+		if n2 == n1 {
+			continue
+		}
+
+		// Project the source colour onto the line n1->n2 to find the ratio of n2:
+		n2v := lightVals(n2c, light)
+		var dot, lensq float64
+		for k := range cv {
+			d0, d1 := float64(cv[k]-n1v[k]), float64(n2v[k]-n1v[k])
+			dot += d0 * d1
+			lensq += d1 * d1
+		}
+		if dot <= 0 || lensq == 0 {
+			continue
+		}
+
+		// Number of cells out of 16 that should use n2, rounded:
+		n2cells := int(dot*16/lensq + 0.5)
+		if n2cells > 16 {
+			n2cells = 16
+		}
+		for j, m := range bayer4x4 {
+			if int(m) < n2cells {
+				cells[j] = mapIndex(n2)
+			}
+		}
+	}
+
+	return out
+}
+
+// RemapPaletted moves img from its current palette to 'to', in place. img.Palette
+// is replaced with to.ColorPalette().
+//
+// If idx is nil, to.Index() is used, otherwise it must be an Index of 'to'. Pixels
+// that refer to entries outside img's current palette are mapped to 0.
+//
+func RemapPaletted(img *image.Paletted, to Palette, idx Index, dither RemapDither) {
+	remapPix(img.Pix, img.Stride, img.Rect.Size(), img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y),
+		ConvertPalette(img.Palette), to, idx, dither)
+	img.Palette = to.ColorPalette()
+}
+
+// Remap moves p from its current palette to 'to', in place. p.Palette is replaced
+// with 'to', and p.Index is replaced with idx.
+//
+// See RemapPaletted for details.
+//
+func (p *Paletted) Remap(to Palette, idx Index, dither RemapDither) {
+	if idx == nil {
+		idx = to.Index()
+	}
+	remapPix(p.Idx, p.Stride, p.Size, 0, p.Palette, to, idx, dither)
+	p.Palette, p.Index = to, idx
+}
+
+func remapPix(pix []uint8, stride int, size image.Point, start int, from, to Palette, idx Index, dither RemapDither) {
+	if size.X <= 0 || size.Y <= 0 {
+		return
+	}
+	if idx == nil {
+		idx = to.Index()
+	}
+
+	switch dither {
+	case RemapNearest:
+		applyRemapTable(pix, stride, size, start, RemapTable(from, idx))
+
+	case RemapOrdered4x4, RemapOrderedLinear4x4:
+		light := GammaLight
+		if dither == RemapOrderedLinear4x4 {
+			light = LinearLight
+		}
+
+		// Use a full-sized table so out-of-range pixels don't need a bounds check:
+		var table [256][16]uint8
+		copy(table[:], remapOrderedTable(from, idx, light))
+		for y := 0; y < size.Y; y++ {
+			row := pix[start+y*stride : start+y*stride+size.X]
+			cy := (y & 3) << 2
+			for x, v := range row {
+				row[x] = table[v][cy|(x&3)]
+			}
+		}
+
+	default:
+		panic("rgba: unknown RemapDither")
+	}
+}
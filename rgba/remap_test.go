@@ -0,0 +1,191 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"math/rand"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func TestRemapPaletted(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	gen := testimg.RandBlocks{W: 64, H: 64, BlockW: 2, BlockH: 2}
+
+	from := ConvertPalette(palette.Plan9)
+	to := ConvertPalette(palette.WebSafe[:64])
+	idx := to.Index()
+
+	img := gen.Paletted(rng, from.ColorPalette())
+	orig := make([]uint8, len(img.Pix))
+	copy(orig, img.Pix)
+
+	RemapPaletted(img, to, idx, RemapNearest)
+	if len(img.Palette) != len(to) {
+		t.Fatal()
+	}
+	for i, v := range orig {
+		if expected := uint8(idx.NearestRGBAIndex(from[v])); img.Pix[i] != expected {
+			t.Fatalf("expected %d, found %d at %d", expected, img.Pix[i], i)
+		}
+	}
+}
+
+func TestRemapTablePaletteTooLarge(t *testing.T) {
+	for name, remap := range map[string]func(){
+		"nearest": func() { RemapTable(Palette{{0, 0, 0, 0xff}}, largeIndex{}) },
+		"ordered": func() { RemapOrderedTable(Palette{{0, 0, 0, 0xff}}, largeIndex{}) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			remap()
+		})
+	}
+}
+
+func TestRemapOrdered(t *testing.T) {
+	from := Palette{
+		{0x40, 0x40, 0x40, 0xff},
+		{0xff, 0xff, 0xff, 0xff},
+	}
+	to := Palette{
+		{0x00, 0x00, 0x00, 0xff},
+		{0xff, 0xff, 0xff, 0xff},
+	}
+
+	pimg := NewPaletted(image.Pt(4, 8), from, nil)
+	for i := 0; i < 16; i++ {
+		pimg.Idx[i] = 0 // grey
+	}
+	for i := 16; i < 32; i++ {
+		pimg.Idx[i] = 1 // white
+	}
+
+	pimg.Remap(to, nil, RemapOrdered4x4)
+
+	var grey, white int
+	for i := 0; i < 16; i++ {
+		grey += int(pimg.Idx[i])
+	}
+	for i := 16; i < 32; i++ {
+		white += int(pimg.Idx[i])
+	}
+
+	// 0x40 is about 1/4 of the way between black and white:
+	if grey != 4 {
+		t.Fatal("expected 4 white cells in grey block, found", grey)
+	}
+	if white != 16 {
+		t.Fatal("exact match was dithered:", white)
+	}
+	if pimg.RGBAAt(0, 0) != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Fatal("expected first cell of bayer matrix to be white")
+	}
+}
+
+func TestRemapOrderedLinear(t *testing.T) {
+	from := Palette{{0xbc, 0xbc, 0xbc, 0xff}}
+	to := Palette{{0x00, 0x00, 0x00, 0xff}, {0xff, 0xff, 0xff, 0xff}}
+
+	for _, tc := range []struct {
+		dither RemapDither
+		white  int
+	}{
+		// 0xBC is about 3/4 of the way between black and white when gamma-encoded,
+		// but half way in linear light:
+		{RemapOrdered4x4, 12},
+		{RemapOrderedLinear4x4, 8},
+	} {
+		pimg := NewPaletted(image.Pt(4, 4), from, nil)
+		pimg.Remap(to, nil, tc.dither)
+		var white int
+		for _, v := range pimg.Idx {
+			white += int(v)
+		}
+		if white != tc.white {
+			t.Fatalf("dither %d: expected %d white cells, found %d", tc.dither, tc.white, white)
+		}
+	}
+
+	if table := RemapOrderedLinearTable(to, to.Index()); table[0] != [16]uint8{} || table[1] != [16]uint8{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1} {
+		t.Fatal("exact match was dithered:", table)
+	}
+}
+
+func TestRemapOrderedSmooth(t *testing.T) {
+	// A smooth horizontal grey ramp, reduced to four greys. Averaged over each 4x4
+	// tile, the ordered dither should track the ramp more closely than the banding
+	// produced by mapping to the nearest colour:
+	gen := testimg.LinearGradient{W: 256, H: 16}
+	grey := make(color.Palette, 256)
+	for i := range grey {
+		grey[i] = color.Gray{uint8(i)}
+	}
+	to := Palette{{0, 0, 0, 0xff}, {0x55, 0x55, 0x55, 0xff}, {0xaa, 0xaa, 0xaa, 0xff}, {0xff, 0xff, 0xff, 0xff}}
+
+	tileError := func(dither RemapDither) (sum int) {
+		src := gen.Paletted(nil, grey)
+		img := ConvertPaletted(src, nil).CloneDeep()
+		img.Remap(to, nil, dither)
+		for ty := 0; ty < gen.H; ty += 4 {
+			for tx := 0; tx < gen.W; tx += 4 {
+				var want, got int
+				for y := ty; y < ty+4; y++ {
+					for x := tx; x < tx+4; x++ {
+						want += int(src.Pix[src.PixOffset(x, y)])
+						got += int(img.RGBAAt(x, y).R)
+					}
+				}
+				if d := want - got; d < 0 {
+					sum -= d
+				} else {
+					sum += d
+				}
+			}
+		}
+		return sum
+	}
+
+	nearest, ordered := tileError(RemapNearest), tileError(RemapOrdered4x4)
+	if ordered*2 >= nearest {
+		t.Fatalf("ordered tile error %d not much better than nearest %d", ordered, nearest)
+	}
+}
+
+func TestRemapOrderedGolden(t *testing.T) {
+	rng := testimg.NewRNG(0)
+	gen := testimg.RandBlocks{W: 64, H: 64, BlockW: 8, BlockH: 8}
+
+	img := gen.Paletted(rng, palette.Plan9)
+	RemapPaletted(img, ConvertPalette(palette.WebSafe[:64]), nil, RemapOrdered4x4)
+	testimg.AssertGolden(t, "remap-ordered-plan9-websafe64", img, 0)
+}
+
+func BenchmarkRemapPaletted(b *testing.B) {
+	rng := rand.New(rand.NewSource(0))
+	gen := testimg.RandBlocks{W: 512, H: 512, BlockW: 2, BlockH: 2}
+	from := ConvertPalette(palette.Plan9)
+	to := ConvertPalette(palette.WebSafe)
+	idx := to.Index()
+	img := gen.Paletted(rng, from.ColorPalette())
+
+	b.Run("nearest", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			img.Palette = palette.Plan9
+			RemapPaletted(img, to, idx, RemapNearest)
+		}
+	})
+
+	b.Run("ordered", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			img.Palette = palette.Plan9
+			RemapPaletted(img, to, idx, RemapOrdered4x4)
+		}
+	})
+}
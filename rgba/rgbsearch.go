@@ -3,6 +3,7 @@ package rgba
 import (
 	"encoding/binary"
 	"fmt"
+	"image"
 	"image/color"
 )
 
@@ -74,13 +75,36 @@ func (pc *rgbPrecacheIndex) NearestRGBA(c color.RGBA) (nn color.RGBA, idx int) {
 	return nn, idx
 }
 
+func (pc *rgbPrecacheIndex) MapImage(src *Image, dst *image.Paletted) {
+	mapImageParallel(src, dst, pc.mapVals)
+}
+
+func (pc *rgbPrecacheIndex) MapVals(src []color.RGBA, dst []uint8) {
+	mapValsParallel(src, dst, pc.mapVals)
+}
+
+func (pc *rgbPrecacheIndex) mapVals(src []color.RGBA, dst []uint8) {
+	if len(src) == 0 {
+		return
+	}
+	_ = dst[len(src)-1]
+
+	for i, c := range src {
+		dst[i] = uint8(pc.index[c.R>>3][c.G>>3][c.B>>3])
+	}
+}
+
 func (rgbPrecacheIndexer) UnmarshalIndex(data []byte) (Index, error) {
 	if len(data) < 4 {
 		return nil, fmt.Errorf("rgba: invalid data size")
 	}
 
 	var pc rgbPrecacheIndex
-	palsz := int(binary.LittleEndian.Uint32(data))
+	sz := binary.LittleEndian.Uint32(data)
+	if sz > 256 || uint32(len(data)-4)/4 < sz {
+		return nil, fmt.Errorf("rgba: invalid palette size")
+	}
+	palsz := int(sz)
 	pc.pal = make(Palette, palsz)
 	pos := 4
 
@@ -110,8 +134,11 @@ func (rgbPrecacheIndexer) UnmarshalIndex(data []byte) (Index, error) {
 
 		idx = int64(idx + delt)
 
+		if run > int64(32768-c) {
+			return nil, fmt.Errorf("rgba: invalid index")
+		}
 		for r := int64(0); r < run; r, c = r+1, c+1 {
-			if int(idx) >= palsz {
+			if idx < 0 || idx >= int64(palsz) {
 				return nil, fmt.Errorf("rgba: invalid palette index")
 			}
 
@@ -2,6 +2,7 @@ package rgba
 
 import (
 	"fmt"
+	"image"
 	"image/color"
 	"math"
 	"sort"
@@ -44,6 +45,33 @@ func (kd *rgbNode) NearestRGBA(c color.RGBA) (col color.RGBA, idx int) {
 	return node.col, node.index
 }
 
+func (kd *rgbNode) MapImage(src *Image, dst *image.Paletted) {
+	mapImageParallel(src, dst, kd.mapVals)
+}
+
+func (kd *rgbNode) MapVals(src []color.RGBA, dst []uint8) {
+	mapValsParallel(src, dst, kd.mapVals)
+}
+
+func (kd *rgbNode) mapVals(src []color.RGBA, dst []uint8) {
+	if len(src) == 0 {
+		return
+	}
+	_ = dst[len(src)-1]
+
+	// The tree is never built with more than 256 entries, so node.index always fits:
+	last := src[0]
+	node, _ := kd.nnRecursive(last, math.MaxUint32, 0)
+	lastIdx := uint8(node.index)
+	for i, c := range src {
+		if c != last {
+			node, _ = kd.nnRecursive(c, math.MaxUint32, 0)
+			last, lastIdx = c, uint8(node.index)
+		}
+		dst[i] = lastIdx
+	}
+}
+
 func (kd *rgbNode) nnRecursive(c color.RGBA, best uint32, depth int) (nn *rgbNode, bestResult uint32) {
 	// XXX(bw): I tried an iterative approach to this (rather than a recursive one) but it
 	// was about the same speed, and vastly more horrible.
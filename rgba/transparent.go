@@ -0,0 +1,137 @@
+package rgba
+
+import (
+	"image"
+	"image/color"
+)
+
+// NewTransparentIndexer creates an indexer which designates one palette entry as the
+// transparent entry, which is common in GIF and sprite work.
+//
+// Colours with an alpha value below 'threshold' always map to the transparent entry.
+// All other colours are searched for using an index built by 'using' from the palette
+// with the transparent entry removed, so opaque colours can never map to it.
+//
+// If 'transparent' is < 0, the first entry in the palette with an alpha of 0 is used.
+// If there is no such entry, or 'transparent' is out of range, the index built by
+// 'using' is returned as-is.
+//
+// If 'using' is nil, NewRGBTreeIndexer() is used. This works with any Indexer, but
+// the RGB indexers (NewRGBTreeIndexer, NewRGBPrecacheIndexer) are the most useful
+// here as they would otherwise discard alpha entirely.
+//
+func NewTransparentIndexer(using Indexer, transparent int, threshold uint8) Indexer {
+	if using == nil {
+		using = NewRGBTreeIndexer()
+	}
+	return &transparentIndexer{using: using, transparent: transparent, threshold: threshold}
+}
+
+type transparentIndexer struct {
+	using       Indexer
+	transparent int
+	threshold   uint8
+}
+
+func (ti *transparentIndexer) IndexRGBAPalette(pal Palette) Index {
+	trans := ti.transparent
+	if trans < 0 {
+		for i, c := range pal {
+			if c.A == 0 {
+				trans = i
+				break
+			}
+		}
+	}
+	if trans < 0 || trans >= len(pal) {
+		return ti.using.IndexRGBAPalette(pal)
+	}
+
+	idx := &transparentIndex{
+		threshold: ti.threshold,
+		trans:     uint8(trans),
+		transCol:  pal[trans],
+		table:     make([]uint8, 0, len(pal)-1),
+	}
+
+	opaque := make(Palette, 0, len(pal)-1)
+	for i, c := range pal {
+		if i != trans {
+			opaque = append(opaque, c)
+			idx.table = append(idx.table, uint8(i))
+		}
+	}
+	if len(opaque) > 0 {
+		idx.opaque = ti.using.IndexRGBAPalette(opaque)
+	}
+
+	return idx
+}
+
+type transparentIndex struct {
+	threshold uint8
+	trans     uint8
+	transCol  color.RGBA
+
+	// opaque is an index of the palette without the transparent entry. table maps
+	// the indexes it returns back to the original palette. If the palette contained
+	// only the transparent entry, opaque is nil.
+	opaque Index
+	table  []uint8
+}
+
+var (
+	_ IndexMapper  = &transparentIndex{}
+	_ serialMapper = &transparentIndex{}
+)
+
+func (ti *transparentIndex) NearestRGBA(c color.RGBA) (nn color.RGBA, idx int) {
+	if c.A < ti.threshold || ti.opaque == nil {
+		return ti.transCol, int(ti.trans)
+	}
+	nn, idx = ti.opaque.NearestRGBA(c)
+	return nn, int(ti.table[idx])
+}
+
+func (ti *transparentIndex) NearestRGBAIndex(c color.RGBA) int {
+	if c.A < ti.threshold || ti.opaque == nil {
+		return int(ti.trans)
+	}
+	return int(ti.table[ti.opaque.NearestRGBAIndex(c)])
+}
+
+func (ti *transparentIndex) NearestRGBAColor(c color.RGBA) color.RGBA {
+	if c.A < ti.threshold || ti.opaque == nil {
+		return ti.transCol
+	}
+	return ti.opaque.NearestRGBAColor(c)
+}
+
+func (ti *transparentIndex) MapImage(src *Image, dst *image.Paletted) {
+	mapImageParallel(src, dst, ti.mapVals)
+}
+
+func (ti *transparentIndex) MapVals(src []color.RGBA, dst []uint8) {
+	mapValsParallel(src, dst, ti.mapVals)
+}
+
+func (ti *transparentIndex) mapVals(src []color.RGBA, dst []uint8) {
+	if len(src) == 0 {
+		return
+	}
+	_ = dst[len(src)-1]
+
+	if ti.opaque != nil {
+		// We are already inside a worker here, so MapVals would only spawn more
+		// goroutines:
+		mapValsSerial(ti.opaque, src, dst)
+	}
+
+	for i, c := range src {
+		if c.A < ti.threshold || ti.opaque == nil {
+			dst[i] = ti.trans
+		} else {
+			dst[i] = ti.table[dst[i]]
+		}
+	}
+}
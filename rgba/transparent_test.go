@@ -0,0 +1,85 @@
+package rgba
+
+import (
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func TestTransparentIndexer(t *testing.T) {
+	const iter = 10000
+	const threshold = 0x80
+
+	rng := rand.New(rand.NewSource(0))
+
+	// The transparent entry deliberately contains an RGB value that would otherwise
+	// attract a lot of matches:
+	pal := ConvertPalette(testimg.RandPalette(rng, 32))
+	pal[5] = color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0}
+
+	opaque := append(append(Palette{}, pal[:5]...), pal[6:]...)
+
+	for _, tc := range []struct {
+		name  string
+		using Indexer
+		exact bool
+	}{
+		{"rgbtree", NewRGBTreeIndexer(), true},
+		{"rgbprecache", NewRGBPrecacheIndexer(nil), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, trans := range []int{5, -1} {
+				idx := NewTransparentIndexer(tc.using, trans, threshold).IndexRGBAPalette(pal)
+
+				cols := make([]color.RGBA, iter)
+				for i := range cols {
+					cols[i] = testimg.RandRGBA(rng)
+				}
+
+				for _, col := range cols {
+					found := idx.NearestRGBAIndex(col)
+					if col.A < threshold {
+						if found != 5 {
+							t.Fatal("transparent colour", col, "mapped to", found)
+						}
+						continue
+					}
+					if found == 5 {
+						t.Fatal("opaque colour", col, "mapped to transparent entry")
+					}
+					if tc.exact {
+						expected := rgbNearestEuclidean(opaque, col)
+						dist1 := sqDiff8(expected.R, col.R) + sqDiff8(expected.G, col.G) + sqDiff8(expected.B, col.B)
+						dist2 := sqDiff8(pal[found].R, col.R) + sqDiff8(pal[found].G, col.G) + sqDiff8(pal[found].B, col.B)
+						if dist1 != dist2 {
+							t.Fatal("colour", col, "expected", expected, "found", pal[found])
+						}
+					}
+				}
+
+				vals := make([]uint8, len(cols))
+				MapVals(idx, cols, vals)
+				for i, col := range cols {
+					if expected := idx.NearestRGBAIndex(col); int(vals[i]) != expected {
+						t.Fatal("MapVals", i, "expected", expected, "found", vals[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTransparentIndexerNoTransparent(t *testing.T) {
+	pal := Palette{{0xff, 0, 0, 0xff}, {0, 0xff, 0, 0xff}}
+	idx := NewTransparentIndexer(nil, -1, 0x80).IndexRGBAPalette(pal)
+	if _, ok := idx.(*transparentIndex); ok {
+		t.Fatal("expected plain index when palette has no transparent entry")
+	}
+
+	idx = NewTransparentIndexer(nil, 0, 0x80).IndexRGBAPalette(Palette{{}})
+	if idx.NearestRGBAIndex(color.RGBA{0xff, 0xff, 0xff, 0xff}) != 0 {
+		t.Fatal()
+	}
+}
@@ -0,0 +1,301 @@
+// Command testimg writes testimg recipes out as fixture files, so they can be used
+// by tools and languages that can't call testimg directly.
+//
+// Usage:
+//
+//	testimg [options] <spec>...
+//
+// Each spec is parsed by testimg.ParseRecipe, for example:
+//
+//	randblocks:512x512:32x32
+//	perlin:256x256:octaves=4:mono
+//
+// The manifest records the spec as returned by the recipe's String method, which
+// may differ from the one passed on the command line, and the files are named
+// after it.
+//
+// For every spec, format and seed, a PNG and a raw file are written to the output
+// directory. The raw file is the image's Pix slice (or, for ycbcr, its Y, Cb and
+// Cr slices one after the other), exactly as it is in memory. A manifest.json is
+// written alongside them, listing every file with the information needed to load
+// the raw files, and the SHA-256 of each file's contents.
+//
+// Some characters are dropped from the spec to make the file names, so distinct
+// specs can end up with the same name; testimg reports an error rather than
+// write either of them.
+//
+// Seeds are passed to testimg.NewRNG, so the files for a given spec and seed will
+// not change between versions of testimg unless the recipe itself changes.
+//
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+var formats = []string{"rgba", "rgba64", "nrgba", "nrgba64", "paletted", "ycbcr", "cmyk"}
+
+var palettes = map[string]color.Palette{
+	"plan9":   palette.Plan9,
+	"websafe": palette.WebSafe,
+	"rand":    testimg.RandPalette(testimg.NewRNG(0), 256),
+}
+
+type manifestEntry struct {
+	Spec    string `json:"spec"`
+	Format  string `json:"format"`
+	Seed    uint64 `json:"seed"`
+	Palette string `json:"palette,omitempty"`
+
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// MinX and MinY are the image's Bounds().Min. For ycbcr, they are needed to
+	// find the chroma sample for a pixel, as the origin may not be aligned to it.
+	MinX int `json:"minX"`
+	MinY int `json:"minY"`
+
+	// Stride is the stride of the raw file's Pix, or of the Y plane for ycbcr.
+	Stride int `json:"stride"`
+
+	// CStride, SubsampleRatio, CbOffset and CrOffset are only set for ycbcr.
+	// CbOffset and CrOffset are where the Cb and Cr planes start in the raw file.
+	CStride        int    `json:"cstride,omitempty"`
+	SubsampleRatio string `json:"subsampleRatio,omitempty"`
+	CbOffset       int    `json:"cbOffset,omitempty"`
+	CrOffset       int    `json:"crOffset,omitempty"`
+
+	PNG       string `json:"png"`
+	PNGSHA256 string `json:"pngSHA256"`
+	Raw       string `json:"raw"`
+	RawSHA256 string `json:"rawSHA256"`
+}
+
+func run(args []string) error {
+	var (
+		out        = "."
+		formatList = "rgba"
+		seedList   = "0"
+		palName    = "rand"
+	)
+
+	fs := flag.NewFlagSet("testimg", flag.ContinueOnError)
+	fs.StringVar(&out, "out", out, "Directory to write fixtures and manifest.json to")
+	fs.StringVar(&formatList, "formats", formatList, "Comma-separated formats, or 'all': "+strings.Join(formats, ","))
+	fs.StringVar(&seedList, "seeds", seedList, "Comma-separated seeds for testimg.NewRNG")
+	fs.StringVar(&palName, "palette", palName, "Palette for the paletted format: plan9, websafe or rand")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: testimg [options] <spec>...\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nRecipe kinds: %s\n", strings.Join(testimg.RecipeKinds(), ", "))
+	}
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("testimg: no specs")
+	}
+
+	pal, ok := palettes[palName]
+	if !ok {
+		return fmt.Errorf("testimg: unknown palette %q", palName)
+	}
+
+	fmts, err := parseFormats(formatList)
+	if err != nil {
+		return err
+	}
+	seeds, err := parseSeeds(seedList)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(out, 0777); err != nil {
+		return err
+	}
+
+	// Parse every spec and check the file names before writing anything, so a
+	// bad spec doesn't leave a partial set of fixtures behind:
+	var recipes []testimg.Recipe
+	bases := map[string]string{}
+	for _, arg := range fs.Args() {
+		recipe, err := testimg.ParseRecipe(arg)
+		if err != nil {
+			return err
+		}
+		spec := fmt.Sprint(recipe)
+		for _, format := range fmts {
+			for _, seed := range seeds {
+				base := fixtureBase(spec, format, seed)
+				if prev, ok := bases[base]; ok {
+					return fmt.Errorf("testimg: specs %q and %q would both be written to %s", prev, spec, base)
+				}
+				bases[base] = spec
+			}
+		}
+		recipes = append(recipes, recipe)
+	}
+
+	var manifest []manifestEntry
+	for _, recipe := range recipes {
+		for _, format := range fmts {
+			for _, seed := range seeds {
+				entry, err := writeFixture(out, recipe, format, seed, palName, pal)
+				if err != nil {
+					return err
+				}
+				manifest = append(manifest, entry)
+			}
+		}
+	}
+
+	bts, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(out, "manifest.json"), append(bts, '\n'), 0666)
+}
+
+func parseFormats(s string) ([]string, error) {
+	if s == "all" {
+		return formats, nil
+	}
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		found := false
+		for _, known := range formats {
+			if f == known {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("testimg: unknown format %q", f)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+func parseSeeds(s string) ([]uint64, error) {
+	var out []uint64
+	for _, part := range strings.Split(s, ",") {
+		seed, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("testimg: invalid seed %q", part)
+		}
+		out = append(out, seed)
+	}
+	return out, nil
+}
+
+func writeFixture(
+	dir string, recipe testimg.Recipe,
+	format string, seed uint64,
+	palName string, pal color.Palette,
+) (entry manifestEntry, err error) {
+
+	spec := fmt.Sprint(recipe)
+	entry = manifestEntry{Spec: spec, Format: format, Seed: seed}
+
+	rng := testimg.NewRNG(seed)
+
+	var img image.Image
+	var raw []byte
+	switch format {
+	case "rgba":
+		i := recipe.RGBA(rng)
+		img, raw, entry.Stride = i, i.Pix, i.Stride
+	case "rgba64":
+		i := recipe.RGBA64(rng)
+		img, raw, entry.Stride = i, i.Pix, i.Stride
+	case "nrgba":
+		i := recipe.NRGBA(rng)
+		img, raw, entry.Stride = i, i.Pix, i.Stride
+	case "nrgba64":
+		i := recipe.NRGBA64(rng)
+		img, raw, entry.Stride = i, i.Pix, i.Stride
+	case "paletted":
+		i := recipe.Paletted(rng, pal)
+		img, raw, entry.Stride = i, i.Pix, i.Stride
+		entry.Palette = palName
+	case "ycbcr":
+		i := recipe.YCbCr(rng)
+		img, entry.Stride, entry.CStride = i, i.YStride, i.CStride
+		entry.SubsampleRatio = i.SubsampleRatio.String()
+		entry.CbOffset, entry.CrOffset = len(i.Y), len(i.Y)+len(i.Cb)
+		raw = append(append(append([]byte{}, i.Y...), i.Cb...), i.Cr...)
+	case "cmyk":
+		i := recipe.CMYK(rng)
+		img, raw, entry.Stride = i, i.Pix, i.Stride
+	default:
+		return entry, fmt.Errorf("testimg: unknown format %q", format)
+	}
+
+	bounds := img.Bounds()
+	entry.Width, entry.Height = bounds.Dx(), bounds.Dy()
+	entry.MinX, entry.MinY = bounds.Min.X, bounds.Min.Y
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return entry, err
+	}
+
+	base := fixtureBase(spec, format, seed)
+	entry.PNG, entry.Raw = base+".png", base+".raw"
+
+	if entry.PNGSHA256, err = writeFile(filepath.Join(dir, entry.PNG), buf.Bytes()); err != nil {
+		return entry, err
+	}
+	if entry.RawSHA256, err = writeFile(filepath.Join(dir, entry.Raw), raw); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+// fixtureBase returns the name, without an extension, of the files written for
+// spec, format and seed. Distinct specs may share a name, as fileSafe drops some
+// characters; run rejects them rather than let one overwrite the other.
+func fixtureBase(spec, format string, seed uint64) string {
+	return fmt.Sprintf("%s-%s-%d", fileSafe.Replace(spec), format, seed)
+}
+
+// fileSafe turns a spec into something that can be used in a file name.
+var fileSafe = strings.NewReplacer(
+	":", "-", "(", "", ")", "", "=", "", "#", "", ",", "_", "/", "_", "\\", "_",
+)
+
+// writeFile writes data to file, and returns the hex SHA-256 of data.
+func writeFile(file string, data []byte) (sum string, err error) {
+	if err := ioutil.WriteFile(file, data, 0666); err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:]), nil
+}
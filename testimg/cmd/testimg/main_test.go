@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shabbyrobe/imgx/testimg"
+)
+
+// labelRecipe is registered so that two specs can be made to share a file name:
+// "cmdtestlabel:a=b" and "cmdtestlabel:ab" are distinct, but fileSafe drops the
+// '=' from both.
+type labelRecipe struct {
+	testimg.Plasma
+	label string
+}
+
+func (l labelRecipe) String() string {
+	return "cmdtestlabel:" + l.label
+}
+
+func init() {
+	testimg.RegisterRecipe("cmdtestlabel", func(args []string) (testimg.Recipe, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected label")
+		}
+		return labelRecipe{testimg.Plasma{W: 4, H: 4}, args[0]}, nil
+	})
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testimg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The subimage has a stride wider than its width, and an origin that isn't
+	// aligned to its chroma samples:
+	specs := []string{
+		"randblocks:7x5:2x2",
+		"subimage:(subsampled:(plasma:9x7):420):origin=3,-1:pad=2",
+	}
+	args := append([]string{"-out", dir, "-formats", "all", "-seeds", "0,1"}, specs...)
+	if err := run(args); err != nil {
+		t.Fatal(err)
+	}
+
+	bts, err := ioutil.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest []manifestEntry
+	if err := json.Unmarshal(bts, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest) != len(specs)*len(formats)*2 {
+		t.Fatal("unexpected manifest length", len(manifest))
+	}
+
+	var padded bool
+	for _, entry := range manifest {
+		name := fmt.Sprintf("%s/%s/%d", entry.Spec, entry.Format, entry.Seed)
+		pngData := readChecked(t, dir, entry.PNG, entry.PNGSHA256)
+		raw := readChecked(t, dir, entry.Raw, entry.RawSHA256)
+
+		pimg, err := png.Decode(bytes.NewReader(pngData))
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if size := pimg.Bounds().Size(); size != image.Pt(entry.Width, entry.Height) {
+			t.Fatal(name, "png size", size, "does not match manifest")
+		}
+
+		loaded, err := loadRaw(entry, raw)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		recipe, err := testimg.ParseRecipe(entry.Spec)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		expected := render(recipe, entry.Format, entry.Seed)
+		if loaded.Bounds() != expected.Bounds() {
+			t.Fatal(name, "loaded bounds", loaded.Bounds(), "expected", expected.Bounds())
+		}
+		b := expected.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r1, g1, b1, a1 := loaded.At(x, y).RGBA()
+				r2, g2, b2, a2 := expected.At(x, y).RGBA()
+				if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+					t.Fatal(name, "pixel", x, y, "loaded", loaded.At(x, y), "expected", expected.At(x, y))
+				}
+			}
+		}
+
+		if entry.Format == "ycbcr" && entry.Stride > entry.Width && entry.MinX%2 != 0 {
+			padded = true
+		}
+	}
+	if !padded {
+		t.Fatal("expected a padded ycbcr entry with an odd origin")
+	}
+}
+
+func TestRunDuplicateNames(t *testing.T) {
+	for _, specs := range [][]string{
+		{"cmdtestlabel:a=b", "cmdtestlabel:ab"},
+		{"plasma:4x4", "plasma:4x4"},
+	} {
+		dir, err := ioutil.TempDir("", "testimg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		err = run(append([]string{"-out", dir}, specs...))
+		if err == nil || !strings.Contains(err.Error(), "would both be written") {
+			t.Fatal(specs, "expected duplicate name error, found", err)
+		}
+		if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+			t.Fatal(specs, "expected no files to be written, found", len(files))
+		}
+	}
+}
+
+func readChecked(t *testing.T, dir, file, sum string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256(data)
+	if found := hex.EncodeToString(h[:]); found != sum {
+		t.Fatal(file, "sha256", found, "does not match manifest", sum)
+	}
+	return data
+}
+
+// loadRaw builds an image from a raw file using only what is in the manifest, as
+// a user of the fixtures would.
+func loadRaw(entry manifestEntry, raw []byte) (image.Image, error) {
+	rect := image.Rect(entry.MinX, entry.MinY, entry.MinX+entry.Width, entry.MinY+entry.Height)
+	switch entry.Format {
+	case "rgba":
+		return &image.RGBA{Pix: raw, Stride: entry.Stride, Rect: rect}, nil
+	case "rgba64":
+		return &image.RGBA64{Pix: raw, Stride: entry.Stride, Rect: rect}, nil
+	case "nrgba":
+		return &image.NRGBA{Pix: raw, Stride: entry.Stride, Rect: rect}, nil
+	case "nrgba64":
+		return &image.NRGBA64{Pix: raw, Stride: entry.Stride, Rect: rect}, nil
+	case "paletted":
+		return &image.Paletted{Pix: raw, Stride: entry.Stride, Rect: rect, Palette: palettes[entry.Palette]}, nil
+	case "cmyk":
+		return &image.CMYK{Pix: raw, Stride: entry.Stride, Rect: rect}, nil
+	case "ycbcr":
+		for ratio := image.YCbCrSubsampleRatio444; ratio <= image.YCbCrSubsampleRatio410; ratio++ {
+			if ratio.String() == entry.SubsampleRatio {
+				return &image.YCbCr{
+					Y:              raw[:entry.CbOffset],
+					Cb:             raw[entry.CbOffset:entry.CrOffset],
+					Cr:             raw[entry.CrOffset:],
+					YStride:        entry.Stride,
+					CStride:        entry.CStride,
+					SubsampleRatio: ratio,
+					Rect:           rect,
+				}, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown subsample ratio %q", entry.SubsampleRatio)
+	}
+	return nil, fmt.Errorf("unknown format %q", entry.Format)
+}
+
+func render(recipe testimg.Recipe, format string, seed uint64) image.Image {
+	rng := testimg.NewRNG(seed)
+	switch format {
+	case "rgba":
+		return recipe.RGBA(rng)
+	case "rgba64":
+		return recipe.RGBA64(rng)
+	case "nrgba":
+		return recipe.NRGBA(rng)
+	case "nrgba64":
+		return recipe.NRGBA64(rng)
+	case "paletted":
+		return recipe.Paletted(rng, palettes["rand"])
+	case "ycbcr":
+		return recipe.YCbCr(rng)
+	case "cmyk":
+		return recipe.CMYK(rng)
+	}
+	panic("unknown format " + format)
+}
@@ -0,0 +1,172 @@
+package testimg
+
+import (
+	"image/color"
+)
+
+// AlphaMode controls how ColorDist chooses the alpha of each colour.
+type AlphaMode int
+
+const (
+	// AlphaUniform draws alpha uniformly from [0, 255]. The colour is premultiplied
+	// by it, so the result is always valid premultiplied RGBA.
+	AlphaUniform AlphaMode = iota
+
+	// AlphaOpaque makes every colour fully opaque.
+	AlphaOpaque
+
+	// AlphaSet draws alpha uniformly from ColorDist.Alphas.
+	AlphaSet
+)
+
+// ColorDist describes a distribution of random colours, so tests can aim at the
+// cases indexers and quantizers find hard: duplicate entries, colours that are all
+// close together, greys, and mixtures of opaque and transparent colours.
+//
+// The RGB of each colour is chosen first, without alpha:
+//
+//   - If Seeds is not empty, a seed is chosen at random and each channel is
+//     moved by up to Spread in either direction. The alpha of the seeds is
+//     ignored.
+//   - Otherwise, if Gray is set, a random grey is chosen and each channel is moved
+//     by up to Spread in either direction, so the result is near-grey.
+//   - Otherwise, each channel is uniform.
+//
+// Spread defaults to 8; if it is negative, the seeds or greys are used exactly.
+//
+// Alpha is then chosen according to Alpha, and the colour is premultiplied by it.
+// After that, the colour is replaced with fully transparent black with
+// probability Transparent, which should be in [0, 1].
+//
+// The zero ColorDist draws RGB and alpha uniformly.
+//
+type ColorDist struct {
+	Alpha  AlphaMode
+	Alphas []uint8
+
+	Transparent float64
+
+	Seeds  []color.Color
+	Gray   bool
+	Spread int
+
+	// Unique, if positive, is the number of distinct colours returned by Colors and
+	// Palette. The distinct colours are drawn first, then the rest are chosen from
+	// them at random, so every distinct colour appears at least once. RGBA ignores
+	// Unique.
+	Unique int
+}
+
+// RGBA returns a single colour from the distribution.
+func (d ColorDist) RGBA(rng RNG) color.RGBA {
+	rng = rngOrDefault(rng)
+
+	spread := d.Spread
+	if spread == 0 {
+		spread = 8
+	} else if spread < 0 {
+		spread = 0
+	}
+
+	var c color.NRGBA
+	if len(d.Seeds) > 0 {
+		c = color.NRGBAModel.Convert(d.Seeds[rngIntn(rng, len(d.Seeds))]).(color.NRGBA)
+		c.R, c.G, c.B = jitter8(rng, c.R, spread), jitter8(rng, c.G, spread), jitter8(rng, c.B, spread)
+	} else if d.Gray {
+		v := uint8(rng.Uint64() >> 56)
+		c.R, c.G, c.B = jitter8(rng, v, spread), jitter8(rng, v, spread), jitter8(rng, v, spread)
+	} else {
+		v := rngUint32(rng)
+		c.R, c.G, c.B = uint8(v>>24), uint8(v>>16), uint8(v>>8)
+	}
+
+	switch d.Alpha {
+	case AlphaUniform:
+		c.A = uint8(rng.Uint64() >> 56)
+	case AlphaOpaque:
+		c.A = 0xff
+	case AlphaSet:
+		if len(d.Alphas) == 0 {
+			panic("testimg: ColorDist with AlphaSet missing Alphas")
+		}
+		c.A = d.Alphas[rngIntn(rng, len(d.Alphas))]
+	default:
+		panic("testimg: unknown AlphaMode")
+	}
+
+	if d.Transparent > 0 && rngFloat64(rng) < d.Transparent {
+		return color.RGBA{}
+	}
+	return color.RGBAModel.Convert(c).(color.RGBA)
+}
+
+// Colors returns n colours from the distribution. If Unique is positive, it must
+// not be more than n, and the distribution must be able to produce that many
+// distinct colours.
+func (d ColorDist) Colors(rng RNG, n int) []color.RGBA {
+	rng = rngOrDefault(rng)
+	out := make([]color.RGBA, n)
+	if d.Unique <= 0 {
+		for i := range out {
+			out[i] = d.RGBA(rng)
+		}
+		return out
+	}
+
+	if d.Unique > n {
+		panic("testimg: ColorDist Unique is larger than the number of colours")
+	}
+
+	// Give up eventually if the distribution is too narrow, rather than looping
+	// forever:
+	seen := make(map[color.RGBA]bool, d.Unique)
+	for i, tries := 0, 0; i < d.Unique; tries++ {
+		if tries > d.Unique*1000 {
+			panic("testimg: ColorDist can not produce enough unique colours")
+		}
+		c := d.RGBA(rng)
+		if !seen[c] {
+			seen[c] = true
+			out[i] = c
+			i++
+		}
+	}
+	for i := d.Unique; i < n; i++ {
+		out[i] = out[rngIntn(rng, d.Unique)]
+	}
+
+	// Shuffle, so the duplicates aren't all at the end:
+	for i := n - 1; i > 0; i-- {
+		j := rngIntn(rng, i+1)
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// Palette returns a palette of sz colours from the distribution, as Colors does.
+// sz must be between 1 and 256.
+func (d ColorDist) Palette(rng RNG, sz int) color.Palette {
+	if sz <= 0 || sz > 256 {
+		panic("testimg: palette size must be between 1 and 256")
+	}
+	cols := d.Colors(rng, sz)
+	pal := make(color.Palette, sz)
+	for i, c := range cols {
+		pal[i] = c
+	}
+	return pal
+}
+
+// jitter8 moves v by a uniform amount in [-spread, spread], clamped to [0, 255].
+func jitter8(rng RNG, v uint8, spread int) uint8 {
+	if spread <= 0 {
+		return v
+	}
+	n := int(v) + rngIntn(rng, spread*2+1) - spread
+	if n < 0 {
+		return 0
+	} else if n > 0xff {
+		return 0xff
+	}
+	return uint8(n)
+}
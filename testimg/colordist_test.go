@@ -0,0 +1,58 @@
+package testimg
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestColorDist(t *testing.T) {
+	rng := NewRNG(0)
+	seeds := []color.Color{
+		color.RGBA{0x20, 0x40, 0x80, 0xff},
+		color.RGBA{0xf0, 0xf0, 0x10, 0xff},
+	}
+	for _, dist := range []ColorDist{
+		{Alpha: AlphaOpaque, Unique: 8},
+		{Unique: 1},
+		{Alpha: AlphaOpaque, Seeds: seeds, Spread: 3},
+		{Seeds: seeds, Spread: -1},
+		{Gray: true, Spread: 2},
+		{Alpha: AlphaOpaque, Transparent: 0.25},
+		{Alpha: AlphaSet, Alphas: []uint8{0, 0x80, 0xff}},
+		{},
+	} {
+		for i, c := range dist.Palette(rng, 256) {
+			r, g, b, a := c.RGBA()
+			if r > a || g > a || b > a {
+				t.Fatalf("%+v: invalid premultiplied colour %v at %d", dist, c, i)
+			}
+		}
+	}
+
+	cols := ColorDist{Unique: 5}.Colors(rng, 100)
+	uniq := map[color.RGBA]bool{}
+	for _, c := range cols {
+		uniq[c] = true
+	}
+	if len(uniq) != 5 {
+		t.Fatal("expected 5 unique colours, found", len(uniq))
+	}
+
+	for _, c := range (ColorDist{Alpha: AlphaOpaque, Gray: true, Spread: 2}).Colors(rng, 100) {
+		if absDiff8(c.R, c.G) > 4 || absDiff8(c.G, c.B) > 4 || c.A != 0xff {
+			t.Fatal("expected near-grey opaque colour, found", c)
+		}
+	}
+
+	var transparent int
+	for _, c := range (ColorDist{Alpha: AlphaOpaque, Transparent: 0.5}).Colors(rng, 1000) {
+		if c == (color.RGBA{}) {
+			transparent++
+		} else if c.A != 0xff {
+			t.Fatal("expected opaque or transparent colour, found", c)
+		}
+	}
+	if transparent < 400 || transparent > 600 {
+		t.Fatal("expected about half the colours to be transparent, found", transparent)
+	}
+}
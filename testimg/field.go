@@ -0,0 +1,167 @@
+package testimg
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// field is a function that returns the non-premultiplied colour of the pixel at
+// (x, y). Most recipes other than RandBlocks are built from a field, which is
+// rendered into each of the image types required by Recipe by the render*
+// functions below.
+type field func(x, y int) color.NRGBA64
+
+// fieldRecipe is implemented by recipes that can be expressed as a field. The rng
+// is used to set up any random state before rendering starts; the field itself
+// must be deterministic. If w or h is not positive, f may be nil.
+type fieldRecipe interface {
+	field(rng RNG) (w, h int, f field)
+}
+
+func fieldSetup(r fieldRecipe, rng RNG) (w, h int, f field) {
+	rng = rngOrDefault(rng)
+	w, h, f = r.field(rng)
+	if w <= 0 || h <= 0 {
+		panic("testimg: missing size")
+	}
+	return w, h, f
+}
+
+func renderRGBA(r fieldRecipe, rng RNG) *image.RGBA {
+	w, h, f := fieldSetup(r, rng)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, color.RGBAModel.Convert(f(x, y)).(color.RGBA))
+		}
+	}
+	return img
+}
+
+func renderRGBA64(r fieldRecipe, rng RNG) *image.RGBA64 {
+	w, h, f := fieldSetup(r, rng)
+	img := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA64(x, y, color.RGBA64Model.Convert(f(x, y)).(color.RGBA64))
+		}
+	}
+	return img
+}
+
+func renderNRGBA(r fieldRecipe, rng RNG) *image.NRGBA {
+	w, h, f := fieldSetup(r, rng)
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := f(x, y)
+			img.SetNRGBA(x, y, color.NRGBA{uint8(c.R >> 8), uint8(c.G >> 8), uint8(c.B >> 8), uint8(c.A >> 8)})
+		}
+	}
+	return img
+}
+
+func renderNRGBA64(r fieldRecipe, rng RNG) *image.NRGBA64 {
+	w, h, f := fieldSetup(r, rng)
+	img := image.NewNRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA64(x, y, f(x, y))
+		}
+	}
+	return img
+}
+
+func renderPaletted(r fieldRecipe, rng RNG, palette color.Palette) *image.Paletted {
+	if palette == nil {
+		palette = defaultRandPalette
+	}
+	w, h, f := fieldSetup(r, rng)
+	img := image.NewPaletted(image.Rect(0, 0, w, h), palette)
+
+	// Smooth fields repeat colours a lot, so cache the lookups:
+	cache := map[color.NRGBA64]uint8{}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := f(x, y)
+			idx, ok := cache[c]
+			if !ok {
+				idx = uint8(palette.Index(c))
+				if len(cache) > 4096 {
+					cache = map[color.NRGBA64]uint8{}
+				}
+				cache[c] = idx
+			}
+			img.Pix[y*img.Stride+x] = idx
+		}
+	}
+	return img
+}
+
+// renderYCbCr renders a 4:4:4 image. YCbCr has no alpha, so the field is
+// composited over black.
+func renderYCbCr(r fieldRecipe, rng RNG) *image.YCbCr {
+	return ToYCbCr(renderRGBA(r, rng), image.YCbCrSubsampleRatio444)
+}
+
+// renderCMYK renders the field composited over black, as CMYK has no alpha.
+func renderCMYK(r fieldRecipe, rng RNG) *image.CMYK {
+	w, h, f := fieldSetup(r, rng)
+	img := image.NewCMYK(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBAModel.Convert(f(x, y)).(color.RGBA)
+			cc, cm, cy, ck := color.RGBToCMYK(c.R, c.G, c.B)
+			img.SetCMYK(x, y, color.CMYK{C: cc, M: cm, Y: cy, K: ck})
+		}
+	}
+	return img
+}
+
+// unitColor builds a colour from channels in the range [0, 1]. Values outside the
+// range are clamped.
+func unitColor(r, g, b, a float64) color.NRGBA64 {
+	return color.NRGBA64{R: unit16(r), G: unit16(g), B: unit16(b), A: unit16(a)}
+}
+
+func unit16(v float64) uint16 {
+	if v <= 0 {
+		return 0
+	} else if v >= 1 {
+		return 0xffff
+	}
+	return uint16(math.Round(v * 0xffff))
+}
+
+// lerpColor interpolates between a and b, where t is in the range [0, 1].
+func lerpColor(a, b color.NRGBA, t float64) color.NRGBA64 {
+	l := func(a, b uint8) float64 {
+		return (float64(a) + (float64(b)-float64(a))*t) / 0xff
+	}
+	return unitColor(l(a.R, b.R), l(a.G, b.G), l(a.B, b.B), l(a.A, b.A))
+}
+
+// over composites the non-premultiplied colour src over dst.
+func over(dst, src color.NRGBA64) color.NRGBA64 {
+	sa, da := float64(src.A)/0xffff, float64(dst.A)/0xffff
+	oa := sa + da*(1-sa)
+	if oa == 0 {
+		return color.NRGBA64{}
+	}
+	ch := func(s, d uint16) float64 {
+		return (float64(s)/0xffff*sa + float64(d)/0xffff*da*(1-sa)) / oa
+	}
+	return unitColor(ch(src.R, dst.R), ch(src.G, dst.G), ch(src.B, dst.B), oa)
+}
+
+// randNRGBA returns a random opaque colour, or a random translucent one if alpha is
+// true.
+func randNRGBA(rng RNG, alpha bool) color.NRGBA {
+	v := rngUint32(rng)
+	c := color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), 0xff}
+	if alpha {
+		c.A = uint8(v)
+	}
+	return c
+}
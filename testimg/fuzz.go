@@ -0,0 +1,266 @@
+package testimg
+
+import (
+	"image"
+	"image/color"
+)
+
+// FuzzMaxSize is the largest width or height of an image decoded by FuzzData.
+// Images are kept small so each fuzz iteration stays fast.
+const FuzzMaxSize = 64
+
+// FuzzData decodes the bytes of a fuzz input into colours, palettes and images,
+// so native Go fuzzing can drive anything that consumes them. Every input,
+// including an empty one, decodes to something valid: premultiplied colours are
+// clamped to their alpha, palette indexes are wrapped to the palette size, and
+// once the input runs out, every read returns zero.
+//
+// FuzzData also implements RNG, so any Recipe can be driven by fuzz input too,
+// though the bytes will be less directly tied to the output.
+//
+type FuzzData struct {
+	data []byte
+}
+
+var _ RNG = &FuzzData{}
+
+// NewFuzzData returns a FuzzData that reads from data. data is not modified.
+func NewFuzzData(data []byte) *FuzzData {
+	return &FuzzData{data: data}
+}
+
+// Len returns the number of bytes that have not yet been read.
+func (f *FuzzData) Len() int {
+	return len(f.data)
+}
+
+// Byte returns the next byte of the input, or zero if it has run out.
+func (f *FuzzData) Byte() uint8 {
+	if len(f.data) == 0 {
+		return 0
+	}
+	b := f.data[0]
+	f.data = f.data[1:]
+	return b
+}
+
+// Uint64 returns the next 8 bytes of the input as a big-endian uint64. Missing
+// bytes are zero.
+func (f *FuzzData) Uint64() uint64 {
+	var v uint64
+	for i := 0; i < 8; i++ {
+		v = v<<8 | uint64(f.Byte())
+	}
+	return v
+}
+
+// fill copies the next len(pix) bytes of input into pix, zeroing the rest.
+func (f *FuzzData) fill(pix []uint8) {
+	n := copy(pix, f.data)
+	f.data = f.data[n:]
+	for i := n; i < len(pix); i++ {
+		pix[i] = 0
+	}
+}
+
+// RGBA reads a valid premultiplied colour from the next 4 bytes, in R, G, B, A
+// order. Colour channels larger than alpha are clamped to it.
+func (f *FuzzData) RGBA() color.RGBA {
+	c := color.RGBA{f.Byte(), f.Byte(), f.Byte(), f.Byte()}
+	c.R, c.G, c.B = min8(c.R, c.A), min8(c.G, c.A), min8(c.B, c.A)
+	return c
+}
+
+// RGBA64 reads a valid premultiplied colour from the next 8 bytes, as RGBA does.
+func (f *FuzzData) RGBA64() color.RGBA64 {
+	u16 := func() uint16 { return uint16(f.Byte())<<8 | uint16(f.Byte()) }
+	c := color.RGBA64{u16(), u16(), u16(), u16()}
+	c.R, c.G, c.B = min16(c.R, c.A), min16(c.G, c.A), min16(c.B, c.A)
+	return c
+}
+
+// Palette reads a palette of between 1 and 256 colours. The first byte is the
+// size minus one, followed by a colour for each entry as read by RGBA.
+func (f *FuzzData) Palette() color.Palette {
+	pal := make(color.Palette, int(f.Byte())+1)
+	for i := range pal {
+		pal[i] = f.RGBA()
+	}
+	return pal
+}
+
+// Image reads an image of any of the types produced by a Recipe. The first byte
+// chooses the type; the rest is read as described by FuzzImage.
+func (f *FuzzData) Image() image.Image {
+	switch f.Byte() % 7 {
+	case 0:
+		return f.rgbaImage()
+	case 1:
+		return f.rgba64Image()
+	case 2:
+		return f.nrgbaImage()
+	case 3:
+		return f.nrgba64Image()
+	case 4:
+		return f.palettedImage(nil)
+	case 5:
+		return f.ycbcrImage()
+	default:
+		return f.cmykImage()
+	}
+}
+
+// layout reads the size, origin and padding of an image. The width and height are
+// each up to FuzzMaxSize, and may be zero. The origin is anywhere from -128 to 127
+// on each axis. The padding is up to 3 pixels on each side of the canvas that the
+// image is a sub-image of, so the stride need not match the width.
+func (f *FuzzData) layout() (canvas, sub image.Rectangle) {
+	w, h := int(f.Byte())%(FuzzMaxSize+1), int(f.Byte())%(FuzzMaxSize+1)
+	ox, oy := int(int8(f.Byte())), int(int8(f.Byte()))
+	pad := int(f.Byte() % 4)
+	sub = image.Rect(ox, oy, ox+w, oy+h)
+	canvas = image.Rect(sub.Min.X-pad, sub.Min.Y-pad, sub.Max.X+pad, sub.Max.Y+pad)
+	return canvas, sub
+}
+
+func (f *FuzzData) rgbaImage() *image.RGBA {
+	cr, sr := f.layout()
+	img := image.NewRGBA(cr)
+	f.fill(img.Pix)
+	for i := 0; i < len(img.Pix); i += 4 {
+		a := img.Pix[i+3]
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2] = min8(img.Pix[i], a), min8(img.Pix[i+1], a), min8(img.Pix[i+2], a)
+	}
+	return img.SubImage(sr).(*image.RGBA)
+}
+
+func (f *FuzzData) rgba64Image() *image.RGBA64 {
+	cr, sr := f.layout()
+	img := image.NewRGBA64(cr)
+	f.fill(img.Pix)
+	for i := 0; i < len(img.Pix); i += 8 {
+		a := uint16(img.Pix[i+6])<<8 | uint16(img.Pix[i+7])
+		for c := 0; c < 6; c += 2 {
+			if v := uint16(img.Pix[i+c])<<8 | uint16(img.Pix[i+c+1]); v > a {
+				img.Pix[i+c], img.Pix[i+c+1] = img.Pix[i+6], img.Pix[i+7]
+			}
+		}
+	}
+	return img.SubImage(sr).(*image.RGBA64)
+}
+
+func (f *FuzzData) nrgbaImage() *image.NRGBA {
+	cr, sr := f.layout()
+	img := image.NewNRGBA(cr)
+	f.fill(img.Pix)
+	return img.SubImage(sr).(*image.NRGBA)
+}
+
+func (f *FuzzData) nrgba64Image() *image.NRGBA64 {
+	cr, sr := f.layout()
+	img := image.NewNRGBA64(cr)
+	f.fill(img.Pix)
+	return img.SubImage(sr).(*image.NRGBA64)
+}
+
+// palettedImage reads a palette from the input if pal is nil.
+func (f *FuzzData) palettedImage(pal color.Palette) *image.Paletted {
+	cr, sr := f.layout()
+	if pal == nil {
+		pal = f.Palette()
+	}
+	img := image.NewPaletted(cr, pal)
+	f.fill(img.Pix)
+	for i, v := range img.Pix {
+		img.Pix[i] = uint8(int(v) % len(pal))
+	}
+	return img.SubImage(sr).(*image.Paletted)
+}
+
+func (f *FuzzData) ycbcrImage() *image.YCbCr {
+	cr, sr := ycbcrNonNegative(f.layout())
+	ratio := YCbCrSubsampleRatios[int(f.Byte())%len(YCbCrSubsampleRatios)]
+	img := image.NewYCbCr(cr, ratio)
+	f.fill(img.Y)
+	f.fill(img.Cb)
+	f.fill(img.Cr)
+	return img.SubImage(sr).(*image.YCbCr)
+}
+
+func (f *FuzzData) cmykImage() *image.CMYK {
+	cr, sr := f.layout()
+	img := image.NewCMYK(cr)
+	f.fill(img.Pix)
+	return img.SubImage(sr).(*image.CMYK)
+}
+
+// FuzzImage is a Recipe that decodes every image from Data, ignoring the rng.
+// Each image is laid out as:
+//
+//	width, height        1 byte each, modulo FuzzMaxSize+1
+//	origin x, origin y   1 signed byte each
+//	padding              1 byte, modulo 4
+//	YCbCr only:          1 byte choosing from YCbCrSubsampleRatios
+//	Paletted only:       a palette as read by FuzzData.Palette, if none is passed
+//	pixels               the Pix (or Y, Cb, Cr) of the whole padded canvas
+//
+// YCbCr images are moved right and down until no part of the canvas is at a
+// negative coordinate, where image.YCbCr can't find the right chroma samples.
+//
+type FuzzImage struct {
+	Data []byte
+}
+
+func (r FuzzImage) RGBA(rng RNG) *image.RGBA       { return NewFuzzData(r.Data).rgbaImage() }
+func (r FuzzImage) RGBA64(rng RNG) *image.RGBA64   { return NewFuzzData(r.Data).rgba64Image() }
+func (r FuzzImage) NRGBA(rng RNG) *image.NRGBA     { return NewFuzzData(r.Data).nrgbaImage() }
+func (r FuzzImage) NRGBA64(rng RNG) *image.NRGBA64 { return NewFuzzData(r.Data).nrgba64Image() }
+func (r FuzzImage) YCbCr(rng RNG) *image.YCbCr     { return NewFuzzData(r.Data).ycbcrImage() }
+func (r FuzzImage) CMYK(rng RNG) *image.CMYK       { return NewFuzzData(r.Data).cmykImage() }
+func (r FuzzImage) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return NewFuzzData(r.Data).palettedImage(pal)
+}
+
+// size returns the size of every image decoded from Data, which may be empty.
+func (r FuzzImage) size() image.Point {
+	_, sub := NewFuzzData(r.Data).layout()
+	return sub.Size()
+}
+
+// FuzzSeeds returns a seed corpus for fuzz targets that use FuzzData.Image. There
+// is an empty input, and for each image type, inputs with and without an origin
+// and padding, followed by enough noise to fill every pixel.
+func FuzzSeeds() [][]byte {
+	seeds := [][]byte{{}}
+	rng := NewRNG(0)
+	for kind := byte(0); kind < 7; kind++ {
+		for _, header := range [][]byte{
+			{kind, 16, 16, 0, 0, 0},
+			{kind, 13, 7, 0xfd, 5, 2},
+		} {
+			// Enough for a palette (always 16 entries) plus a 19x13 canvas of
+			// RGBA64 pixels:
+			seed := append([]byte{}, header...)
+			seed = append(seed, 15)
+			for i := 0; i < 16*4+19*13*8; i++ {
+				seed = append(seed, uint8(rng.Uint64()>>56))
+			}
+			seeds = append(seeds, seed)
+		}
+	}
+	return seeds
+}
+
+func min8(a, b uint8) uint8 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func min16(a, b uint16) uint16 {
+	if a < b {
+		return a
+	}
+	return b
+}
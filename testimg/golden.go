@@ -0,0 +1,270 @@
+package testimg
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// GoldenDir is the directory, relative to the package under test, that
+// AssertGolden reads and writes fixtures in.
+var GoldenDir = "testdata"
+
+// goldenUpdateFlag is looked up when AssertGolden is called, rather than registered
+// by testimg, so it doesn't turn up in every binary that imports testimg. Packages
+// that want it declare it themselves.
+const goldenUpdateFlag = "update"
+
+// goldenUpdateEnv can be set to regenerate golden images in packages that don't
+// declare goldenUpdateFlag.
+const goldenUpdateEnv = "TESTIMG_UPDATE"
+
+func goldenUpdate() bool {
+	if v, err := strconv.ParseBool(os.Getenv(goldenUpdateEnv)); err == nil && v {
+		return true
+	}
+	f := flag.Lookup(goldenUpdateFlag)
+	if f == nil {
+		return false
+	}
+	g, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	v, _ := g.Get().(bool)
+	return v
+}
+
+// AssertGolden compares img with the PNG fixture at GoldenDir/name.png. If any
+// channel of any pixel differs by more than tolerance, the test fails and a diff
+// image is written to GoldenDir/name.diff.png (see DiffImage). The diff image is
+// removed again when the test next passes.
+//
+// If the TESTIMG_UPDATE environment variable is set to a true value, the fixture is
+// written from img instead, and the test does not fail. The same goes for an
+// "-update" flag, if the package under test declares one:
+//
+//	var _ = flag.Bool("update", false, "regenerate golden images")
+//
+// img is round-tripped through the PNG encoder before it is compared, so precision
+// lost to PNG's non-premultiplied storage does not count as a difference.
+//
+func AssertGolden(t testing.TB, name string, img image.Image, tolerance uint8) {
+	t.Helper()
+
+	file := filepath.Join(GoldenDir, name+".png")
+	diffFile := filepath.Join(GoldenDir, name+".diff.png")
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("testimg: could not encode %q: %v", name, err)
+	}
+
+	if goldenUpdate() {
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			t.Fatalf("testimg: could not create golden dir: %v", err)
+		}
+		if err := ioutil.WriteFile(file, buf.Bytes(), 0666); err != nil {
+			t.Fatalf("testimg: could not write golden %q: %v", file, err)
+		}
+		os.Remove(diffFile)
+		return
+	}
+
+	got, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("testimg: could not decode %q: %v", name, err)
+	}
+
+	want, err := decodePNGFile(file)
+	if os.IsNotExist(err) {
+		t.Fatalf("testimg: golden %q does not exist; run the test with %s=1 to create it", file, goldenUpdateEnv)
+	} else if err != nil {
+		t.Fatalf("testimg: could not read golden %q: %v", file, err)
+	}
+
+	d := Compare(want, got, tolerance)
+	if d.Passed() {
+		os.Remove(diffFile)
+		return
+	}
+
+	msg := fmt.Sprintf("testimg: image does not match golden %q: %s", file, d)
+	if d.SizeMismatch {
+		t.Fatal(msg)
+	}
+	if err := encodePNGFile(DiffImage(want, got, tolerance), diffFile); err != nil {
+		t.Fatalf("%s; could not write diff: %v", msg, err)
+	}
+	t.Fatalf("%s; diff written to %q", msg, diffFile)
+}
+
+// AssertImage fails the test if any channel of any pixel in got differs from want
+// by more than tolerance. Pixels are compared as 8-bit premultiplied colours,
+// relative to each image's bounds.Min.
+//
+// If the images are small enough to read as text, both are included in the failure
+// message using Dump.
+//
+func AssertImage(t testing.TB, want, got image.Image, tolerance uint8) {
+	t.Helper()
+	d := Compare(want, got, tolerance)
+	if d.Passed() {
+		return
+	}
+	if wb, gb := want.Bounds(), got.Bounds(); wb.Dx() <= assertDumpMax && wb.Dy() <= assertDumpMax &&
+		gb.Dx() <= assertDumpMax && gb.Dy() <= assertDumpMax {
+		t.Fatalf("testimg: images do not match: %s\nwant:\n%s\ngot:\n%s", d, Dump(want, wb), Dump(got, gb))
+	}
+	t.Fatalf("testimg: images do not match: %s", d)
+}
+
+// assertDumpMax is the largest width or height of an image that AssertImage will
+// print as text.
+const assertDumpMax = 32
+
+// Difference reports the result of comparing two images with Compare.
+type Difference struct {
+	// SizeMismatch is true if the images were not the same size. No other fields
+	// are set if so.
+	SizeMismatch bool
+	WantSize     image.Point
+	GotSize      image.Point
+
+	// Mismatched is the number of pixels with a channel that differed by more than
+	// the tolerance.
+	Mismatched int
+
+	// MaxDelta is the largest difference found in any channel. First is the
+	// location of the first mismatched pixel, relative to bounds.Min, and Want and
+	// Got are the colours found there.
+	MaxDelta uint8
+	First    image.Point
+	Want     color.RGBA
+	Got      color.RGBA
+}
+
+// Passed returns true if the images were considered equal.
+func (d Difference) Passed() bool {
+	return !d.SizeMismatch && d.Mismatched == 0
+}
+
+func (d Difference) String() string {
+	if d.SizeMismatch {
+		return fmt.Sprintf("size %v != %v", d.GotSize, d.WantSize)
+	}
+	if d.Mismatched == 0 {
+		return "images match"
+	}
+	return fmt.Sprintf("%d pixels mismatched, max delta %d, first at %v: got %v, want %v",
+		d.Mismatched, d.MaxDelta, d.First, d.Got, d.Want)
+}
+
+// Compare compares want and got pixel by pixel as 8-bit premultiplied colours,
+// relative to each image's bounds.Min.
+func Compare(want, got image.Image, tolerance uint8) (d Difference) {
+	wb, gb := want.Bounds(), got.Bounds()
+	if wb.Size() != gb.Size() {
+		d.SizeMismatch, d.WantSize, d.GotSize = true, wb.Size(), gb.Size()
+		return d
+	}
+
+	for y := 0; y < wb.Dy(); y++ {
+		for x := 0; x < wb.Dx(); x++ {
+			wc := rgbaAt(want, wb.Min.X+x, wb.Min.Y+y)
+			gc := rgbaAt(got, gb.Min.X+x, gb.Min.Y+y)
+			delta := maxChannelDelta(wc, gc)
+			if delta > d.MaxDelta {
+				d.MaxDelta = delta
+			}
+			if delta > tolerance {
+				if d.Mismatched == 0 {
+					d.First, d.Want, d.Got = image.Pt(x, y), wc, gc
+				}
+				d.Mismatched++
+			}
+		}
+	}
+	return d
+}
+
+// DiffImage returns an image the size of want that shows where got differs from
+// it. Pixels that differ by more than tolerance are red, brighter for larger
+// differences; the rest are a faded greyscale copy of want. Both images must be
+// the same size.
+//
+// This draws the same image as rgba.DiffImage, which testimg can't import.
+//
+func DiffImage(want, got image.Image, tolerance uint8) *image.NRGBA {
+	wb, gb := want.Bounds(), got.Bounds()
+	if wb.Size() != gb.Size() {
+		panic("testimg: image sizes do not match")
+	}
+
+	out := image.NewNRGBA(image.Rect(0, 0, wb.Dx(), wb.Dy()))
+	for y := 0; y < wb.Dy(); y++ {
+		for x := 0; x < wb.Dx(); x++ {
+			wc := rgbaAt(want, wb.Min.X+x, wb.Min.Y+y)
+			gc := rgbaAt(got, gb.Min.X+x, gb.Min.Y+y)
+			if delta := maxChannelDelta(wc, gc); delta > tolerance {
+				out.SetNRGBA(x, y, color.NRGBA{R: uint8(0x80 + uint32(delta)*0x7f/0xff), A: 0xff})
+			} else {
+				// Rec. 709 luma, as rgba uses:
+				l := uint8((2126*uint32(wc.R) + 7152*uint32(wc.G) + 722*uint32(wc.B)) / 10000 / 4)
+				out.SetNRGBA(x, y, color.NRGBA{R: 0xbf + l, G: 0xbf + l, B: 0xbf + l, A: 0xff})
+			}
+		}
+	}
+	return out
+}
+
+func rgbaAt(img image.Image, x, y int) color.RGBA {
+	r, g, b, a := img.At(x, y).RGBA()
+	return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+}
+
+func maxChannelDelta(a, b color.RGBA) uint8 {
+	m := absDiff8(a.R, b.R)
+	if v := absDiff8(a.G, b.G); v > m {
+		m = v
+	}
+	if v := absDiff8(a.B, b.B); v > m {
+		m = v
+	}
+	if v := absDiff8(a.A, b.A); v > m {
+		m = v
+	}
+	return m
+}
+
+func absDiff8(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func decodePNGFile(file string) (image.Image, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+func encodePNGFile(img image.Image, file string) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, buf.Bytes(), 0666)
+}
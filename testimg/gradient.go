@@ -0,0 +1,124 @@
+package testimg
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// LinearGradient blends From into To along a line at Angle degrees clockwise from
+// the positive X axis. If both colours are zero, opaque black and white are used.
+type LinearGradient struct {
+	W, H     int
+	From, To color.NRGBA
+	Angle    float64
+}
+
+func (g LinearGradient) field(rng RNG) (w, h int, f field) {
+	from, to := defaultColors(g.From, g.To)
+	rad := g.Angle * math.Pi / 180
+	dx, dy := math.Cos(rad), math.Sin(rad)
+
+	// Project each corner onto the gradient line so the whole image is covered
+	// from exactly 0 to 1:
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, c := range [][2]float64{{0, 0}, {float64(g.W - 1), 0}, {0, float64(g.H - 1)}, {float64(g.W - 1), float64(g.H - 1)}} {
+		p := c[0]*dx + c[1]*dy
+		lo, hi = math.Min(lo, p), math.Max(hi, p)
+	}
+	span := hi - lo
+	if span == 0 {
+		span = 1
+	}
+
+	return g.W, g.H, func(x, y int) color.NRGBA64 {
+		t := (float64(x)*dx + float64(y)*dy - lo) / span
+		return lerpColor(from, to, t)
+	}
+}
+
+func (g LinearGradient) RGBA(rng RNG) *image.RGBA       { return renderRGBA(g, rng) }
+func (g LinearGradient) RGBA64(rng RNG) *image.RGBA64   { return renderRGBA64(g, rng) }
+func (g LinearGradient) NRGBA(rng RNG) *image.NRGBA     { return renderNRGBA(g, rng) }
+func (g LinearGradient) NRGBA64(rng RNG) *image.NRGBA64 { return renderNRGBA64(g, rng) }
+func (g LinearGradient) YCbCr(rng RNG) *image.YCbCr     { return renderYCbCr(g, rng) }
+func (g LinearGradient) CMYK(rng RNG) *image.CMYK       { return renderCMYK(g, rng) }
+func (g LinearGradient) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return renderPaletted(g, rng, pal)
+}
+
+// RadialGradient blends Inner at the centre of the image into Outer at the
+// corners. If both colours are zero, opaque white and black are used.
+type RadialGradient struct {
+	W, H         int
+	Inner, Outer color.NRGBA
+}
+
+func (g RadialGradient) field(rng RNG) (w, h int, f field) {
+	inner, outer := g.Inner, g.Outer
+	if inner == (color.NRGBA{}) && outer == (color.NRGBA{}) {
+		outer, inner = defaultColors(inner, outer)
+	}
+	cx, cy := float64(g.W-1)/2, float64(g.H-1)/2
+	radius := math.Hypot(cx, cy)
+	if radius == 0 {
+		radius = 1
+	}
+	return g.W, g.H, func(x, y int) color.NRGBA64 {
+		t := math.Hypot(float64(x)-cx, float64(y)-cy) / radius
+		return lerpColor(inner, outer, t)
+	}
+}
+
+func (g RadialGradient) RGBA(rng RNG) *image.RGBA       { return renderRGBA(g, rng) }
+func (g RadialGradient) RGBA64(rng RNG) *image.RGBA64   { return renderRGBA64(g, rng) }
+func (g RadialGradient) NRGBA(rng RNG) *image.NRGBA     { return renderNRGBA(g, rng) }
+func (g RadialGradient) NRGBA64(rng RNG) *image.NRGBA64 { return renderNRGBA64(g, rng) }
+func (g RadialGradient) YCbCr(rng RNG) *image.YCbCr     { return renderYCbCr(g, rng) }
+func (g RadialGradient) CMYK(rng RNG) *image.CMYK       { return renderCMYK(g, rng) }
+func (g RadialGradient) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return renderPaletted(g, rng, pal)
+}
+
+// Checkerboard alternates cells of A and B, starting with A in the top left. If
+// both colours are zero, opaque black and white are used. Cells default to 8x8.
+type Checkerboard struct {
+	W, H         int
+	CellW, CellH int
+	A, B         color.NRGBA
+}
+
+func (c Checkerboard) field(rng RNG) (w, h int, f field) {
+	a, b := defaultColors(c.A, c.B)
+	ca, cb := color.NRGBA64Model.Convert(a).(color.NRGBA64), color.NRGBA64Model.Convert(b).(color.NRGBA64)
+	cw, ch := c.CellW, c.CellH
+	if cw <= 0 {
+		cw = 8
+	}
+	if ch <= 0 {
+		ch = 8
+	}
+	return c.W, c.H, func(x, y int) color.NRGBA64 {
+		if (x/cw+y/ch)%2 == 0 {
+			return ca
+		}
+		return cb
+	}
+}
+
+func (c Checkerboard) RGBA(rng RNG) *image.RGBA       { return renderRGBA(c, rng) }
+func (c Checkerboard) RGBA64(rng RNG) *image.RGBA64   { return renderRGBA64(c, rng) }
+func (c Checkerboard) NRGBA(rng RNG) *image.NRGBA     { return renderNRGBA(c, rng) }
+func (c Checkerboard) NRGBA64(rng RNG) *image.NRGBA64 { return renderNRGBA64(c, rng) }
+func (c Checkerboard) YCbCr(rng RNG) *image.YCbCr     { return renderYCbCr(c, rng) }
+func (c Checkerboard) CMYK(rng RNG) *image.CMYK       { return renderCMYK(c, rng) }
+func (c Checkerboard) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return renderPaletted(c, rng, pal)
+}
+
+func defaultColors(a, b color.NRGBA) (color.NRGBA, color.NRGBA) {
+	if a == (color.NRGBA{}) && b == (color.NRGBA{}) {
+		return color.NRGBA{0, 0, 0, 0xff}, color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	}
+	return a, b
+}
@@ -1,7 +1,3 @@
 package testimg
 
-import "math/rand"
-
-var defaultRNG = rand.New(rand.NewSource(0))
-
-var defaultRandPalette = RandPalette(defaultRNG, 256)
+var defaultRandPalette = RandPalette(NewRNG(0), 256)
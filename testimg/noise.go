@@ -0,0 +1,282 @@
+package testimg
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// WhiteNoise gives every pixel an independent random colour. If Mono is set, the
+// pixels are grey. If Alpha is set, the alpha channel is random too.
+type WhiteNoise struct {
+	W, H  int
+	Mono  bool
+	Alpha bool
+}
+
+func (n WhiteNoise) field(rng RNG) (w, h int, f field) {
+	if n.W <= 0 || n.H <= 0 {
+		return n.W, n.H, nil
+	}
+	vals := make([]color.NRGBA64, n.W*n.H)
+	for i := range vals {
+		v := rng.Uint64()
+		c := color.NRGBA64{uint16(v >> 48), uint16(v >> 32), uint16(v >> 16), 0xffff}
+		if n.Mono {
+			c.G, c.B = c.R, c.R
+		}
+		if n.Alpha {
+			c.A = uint16(v)
+		}
+		vals[i] = c
+	}
+	return n.W, n.H, func(x, y int) color.NRGBA64 { return vals[y*n.W+x] }
+}
+
+func (n WhiteNoise) RGBA(rng RNG) *image.RGBA       { return renderRGBA(n, rng) }
+func (n WhiteNoise) RGBA64(rng RNG) *image.RGBA64   { return renderRGBA64(n, rng) }
+func (n WhiteNoise) NRGBA(rng RNG) *image.NRGBA     { return renderNRGBA(n, rng) }
+func (n WhiteNoise) NRGBA64(rng RNG) *image.NRGBA64 { return renderNRGBA64(n, rng) }
+func (n WhiteNoise) YCbCr(rng RNG) *image.YCbCr     { return renderYCbCr(n, rng) }
+func (n WhiteNoise) CMYK(rng RNG) *image.CMYK       { return renderCMYK(n, rng) }
+func (n WhiteNoise) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return renderPaletted(n, rng, pal)
+}
+
+// ValueNoise interpolates smoothly between random values placed on a lattice
+// Scale pixels apart (default 32). Octaves (default 1) adds further layers at
+// double the frequency and half the amplitude of the last, for a cloudier look.
+// If Mono is set, the pixels are grey.
+type ValueNoise struct {
+	W, H    int
+	Scale   int
+	Octaves int
+	Mono    bool
+}
+
+func (n ValueNoise) field(rng RNG) (w, h int, f field) {
+	lattice := func() func(x, y float64) float64 {
+		var vals [256]float64
+		for i := range vals {
+			vals[i] = rngFloat64(rng)
+		}
+		perm := rngPerm(rng, 256)
+		at := func(x, y int) float64 {
+			return vals[perm[(perm[x&0xff]+y)&0xff]]
+		}
+		return func(x, y float64) float64 {
+			x0, y0 := math.Floor(x), math.Floor(y)
+			tx, ty := smoothstep(x-x0), smoothstep(y-y0)
+			ix, iy := int(x0), int(y0)
+			top := lerp(at(ix, iy), at(ix+1, iy), tx)
+			bot := lerp(at(ix, iy+1), at(ix+1, iy+1), tx)
+			return lerp(top, bot, ty)
+		}
+	}
+	return n.W, n.H, octaveField(n.Scale, n.Octaves, n.Mono, lattice)
+}
+
+func (n ValueNoise) RGBA(rng RNG) *image.RGBA       { return renderRGBA(n, rng) }
+func (n ValueNoise) RGBA64(rng RNG) *image.RGBA64   { return renderRGBA64(n, rng) }
+func (n ValueNoise) NRGBA(rng RNG) *image.NRGBA     { return renderNRGBA(n, rng) }
+func (n ValueNoise) NRGBA64(rng RNG) *image.NRGBA64 { return renderNRGBA64(n, rng) }
+func (n ValueNoise) YCbCr(rng RNG) *image.YCbCr     { return renderYCbCr(n, rng) }
+func (n ValueNoise) CMYK(rng RNG) *image.CMYK       { return renderCMYK(n, rng) }
+func (n ValueNoise) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return renderPaletted(n, rng, pal)
+}
+
+// PerlinNoise is gradient noise: Perlin's "improved noise", or simplex noise if
+// Simplex is set. Scale and Octaves work as for ValueNoise. If Mono is set, the
+// pixels are grey.
+type PerlinNoise struct {
+	W, H    int
+	Scale   int
+	Octaves int
+	Mono    bool
+	Simplex bool
+}
+
+func (n PerlinNoise) field(rng RNG) (w, h int, f field) {
+	lattice := func() func(x, y float64) float64 {
+		var perm [512]int
+		for i, v := range rngPerm(rng, 256) {
+			perm[i], perm[i+256] = v, v
+		}
+		if n.Simplex {
+			return func(x, y float64) float64 { return simplex2(&perm, x, y)*0.5 + 0.5 }
+		}
+		return func(x, y float64) float64 { return perlin2(&perm, x, y)*0.5 + 0.5 }
+	}
+	return n.W, n.H, octaveField(n.Scale, n.Octaves, n.Mono, lattice)
+}
+
+func (n PerlinNoise) RGBA(rng RNG) *image.RGBA       { return renderRGBA(n, rng) }
+func (n PerlinNoise) RGBA64(rng RNG) *image.RGBA64   { return renderRGBA64(n, rng) }
+func (n PerlinNoise) NRGBA(rng RNG) *image.NRGBA     { return renderNRGBA(n, rng) }
+func (n PerlinNoise) NRGBA64(rng RNG) *image.NRGBA64 { return renderNRGBA64(n, rng) }
+func (n PerlinNoise) YCbCr(rng RNG) *image.YCbCr     { return renderYCbCr(n, rng) }
+func (n PerlinNoise) CMYK(rng RNG) *image.CMYK       { return renderCMYK(n, rng) }
+func (n PerlinNoise) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return renderPaletted(n, rng, pal)
+}
+
+// Plasma is the old demoscene effect: a sum of sine waves in each channel, with
+// random frequencies and phases.
+type Plasma struct {
+	W, H int
+}
+
+func (p Plasma) field(rng RNG) (w, h int, f field) {
+	type wave struct{ fx, fy, fr, phase float64 }
+	var waves [3][3]wave
+	for c := range waves {
+		for i := range waves[c] {
+			waves[c][i] = wave{
+				fx:    (rngFloat64(rng) - 0.5) * 0.1,
+				fy:    (rngFloat64(rng) - 0.5) * 0.1,
+				fr:    rngFloat64(rng) * 0.05,
+				phase: rngFloat64(rng) * 2 * math.Pi,
+			}
+		}
+	}
+	cx, cy := float64(p.W)/2, float64(p.H)/2
+
+	return p.W, p.H, func(x, y int) color.NRGBA64 {
+		fx, fy := float64(x), float64(y)
+		r := math.Hypot(fx-cx, fy-cy)
+		var ch [3]float64
+		for c := range waves {
+			var sum float64
+			for _, w := range waves[c] {
+				sum += math.Sin(fx*w.fx + fy*w.fy + r*w.fr + w.phase)
+			}
+			ch[c] = sum/6 + 0.5
+		}
+		return unitColor(ch[0], ch[1], ch[2], 1)
+	}
+}
+
+func (p Plasma) RGBA(rng RNG) *image.RGBA       { return renderRGBA(p, rng) }
+func (p Plasma) RGBA64(rng RNG) *image.RGBA64   { return renderRGBA64(p, rng) }
+func (p Plasma) NRGBA(rng RNG) *image.NRGBA     { return renderNRGBA(p, rng) }
+func (p Plasma) NRGBA64(rng RNG) *image.NRGBA64 { return renderNRGBA64(p, rng) }
+func (p Plasma) YCbCr(rng RNG) *image.YCbCr     { return renderYCbCr(p, rng) }
+func (p Plasma) CMYK(rng RNG) *image.CMYK       { return renderCMYK(p, rng) }
+func (p Plasma) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return renderPaletted(p, rng, pal)
+}
+
+// octaveField sums octaves of a noise function built by newNoise, which should
+// return values in the range [0, 1]. A separate noise function is built for each
+// channel unless mono is set.
+func octaveField(scale, octaves int, mono bool, newNoise func() func(x, y float64) float64) field {
+	if scale <= 0 {
+		scale = 32
+	}
+	if octaves <= 0 {
+		octaves = 1
+	}
+	chans := 3
+	if mono {
+		chans = 1
+	}
+	noise := make([]func(x, y float64) float64, chans)
+	for i := range noise {
+		noise[i] = newNoise()
+	}
+
+	sample := func(fn func(x, y float64) float64, x, y int) float64 {
+		var sum, amp, norm float64 = 0, 1, 0
+		freq := 1 / float64(scale)
+		for o := 0; o < octaves; o++ {
+			sum += fn(float64(x)*freq, float64(y)*freq) * amp
+			norm += amp
+			amp /= 2
+			freq *= 2
+		}
+		return sum / norm
+	}
+
+	return func(x, y int) color.NRGBA64 {
+		if mono {
+			v := sample(noise[0], x, y)
+			return unitColor(v, v, v, 1)
+		}
+		return unitColor(sample(noise[0], x, y), sample(noise[1], x, y), sample(noise[2], x, y), 1)
+	}
+}
+
+func lerp(a, b, t float64) float64 { return a + (b-a)*t }
+
+func smoothstep(t float64) float64 { return t * t * (3 - 2*t) }
+
+func fade(t float64) float64 { return t * t * t * (t*(t*6-15) + 10) }
+
+// perlin2 is 2D improved Perlin noise, in the range [-1, 1].
+func perlin2(perm *[512]int, x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	xi, yi := int(x0)&0xff, int(y0)&0xff
+	xf, yf := x-x0, y-y0
+	u, v := fade(xf), fade(yf)
+
+	aa := perm[perm[xi]+yi]
+	ab := perm[perm[xi]+yi+1]
+	ba := perm[perm[xi+1]+yi]
+	bb := perm[perm[xi+1]+yi+1]
+
+	top := lerp(grad2(aa, xf, yf), grad2(ba, xf-1, yf), u)
+	bot := lerp(grad2(ab, xf, yf-1), grad2(bb, xf-1, yf-1), u)
+
+	// The theoretical range of 2D Perlin noise is ±sqrt(0.5):
+	return lerp(top, bot, v) * math.Sqrt2
+}
+
+var grad2Dirs = [8][2]float64{
+	{1, 0}, {-1, 0}, {0, 1}, {0, -1},
+	{math.Sqrt2 / 2, math.Sqrt2 / 2}, {-math.Sqrt2 / 2, math.Sqrt2 / 2},
+	{math.Sqrt2 / 2, -math.Sqrt2 / 2}, {-math.Sqrt2 / 2, -math.Sqrt2 / 2},
+}
+
+func grad2(hash int, x, y float64) float64 {
+	g := grad2Dirs[hash&7]
+	return g[0]*x + g[1]*y
+}
+
+const (
+	simplexF2 = 0.36602540378443864676 // (sqrt(3)-1)/2
+	simplexG2 = 0.21132486540518711775 // (3-sqrt(3))/6
+)
+
+// simplex2 is 2D simplex noise, in the range [-1, 1].
+func simplex2(perm *[512]int, x, y float64) float64 {
+	s := (x + y) * simplexF2
+	i, j := math.Floor(x+s), math.Floor(y+s)
+	t := (i + j) * simplexG2
+	x0, y0 := x-(i-t), y-(j-t)
+
+	var i1, j1 float64
+	if x0 > y0 {
+		i1 = 1
+	} else {
+		j1 = 1
+	}
+	x1, y1 := x0-i1+simplexG2, y0-j1+simplexG2
+	x2, y2 := x0-1+2*simplexG2, y0-1+2*simplexG2
+
+	ii, jj := int(i)&0xff, int(j)&0xff
+	corner := func(hash int, x, y float64) float64 {
+		t := 0.5 - x*x - y*y
+		if t < 0 {
+			return 0
+		}
+		t *= t
+		return t * t * grad2(hash, x, y)
+	}
+	n := corner(perm[ii+perm[jj]], x0, y0) +
+		corner(perm[ii+int(i1)+perm[jj+int(j1)]], x1, y1) +
+		corner(perm[ii+1+perm[jj+1]], x2, y2)
+
+	// Scale to roughly [-1, 1]; clamped when converted to a colour:
+	return n * 70
+}
@@ -2,28 +2,31 @@ package testimg
 
 import (
 	"image/color"
-	"math/rand"
 )
 
-func RandPalette(rng *rand.Rand, sz int) color.Palette {
+// RandPalette returns sz random opaque colours. Use ColorDist.Palette for control
+// over the distribution.
+func RandPalette(rng RNG, sz int) color.Palette {
 	if sz <= 0 || sz > 256 {
 		panic("size must be between 1 and 256")
 	}
-	if rng == nil {
-		rng = defaultRNG
-	}
+	rng = rngOrDefault(rng)
 
 	pal := make(color.Palette, sz)
 	for i := 0; i < sz; i++ {
-		next := uint32(rng.Uint32())
-		r, g, b := uint8(next<<16), uint8(next<<8), uint8(next)
+		next := rngUint32(rng)
+		r, g, b := uint8(next>>16), uint8(next>>8), uint8(next)
 		pal[i] = color.RGBA{R: r, G: g, B: b, A: 0xff}
 	}
 	return pal
 }
 
-func RandRGBA(rng *rand.Rand) color.RGBA {
-	v := rng.Uint32()
+// RandRGBA returns a random valid premultiplied colour. The channels are drawn
+// uniformly, then the alpha is raised to the largest of them, so the distribution
+// is skewed heavily towards opaque. Use ColorDist.RGBA for control over the
+// distribution.
+func RandRGBA(rng RNG) color.RGBA {
+	v := rngUint32(rngOrDefault(rng))
 	col := color.RGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}
 
 	if col.A < col.R {
@@ -38,8 +41,8 @@ func RandRGBA(rng *rand.Rand) color.RGBA {
 	return col
 }
 
-func RandRGBA64(rng *rand.Rand) color.RGBA64 {
-	v := rng.Uint64()
+func RandRGBA64(rng RNG) color.RGBA64 {
+	v := rngOrDefault(rng).Uint64()
 	col := color.RGBA64{uint16(v >> 48), uint16(v >> 32), uint16(v >> 16), uint16(v)}
 
 	if col.A < col.R {
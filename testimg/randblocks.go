@@ -1,11 +1,8 @@
 package testimg
 
 import (
-	"bytes"
 	"image"
 	"image/color"
-	"image/jpeg"
-	"math/rand"
 )
 
 type RandBlocks struct {
@@ -25,11 +22,9 @@ func (r *RandBlocks) ensureValid() {
 	}
 }
 
-func (r RandBlocks) gen(rng *rand.Rand, makeSet func() func(x, y int)) {
+func (r RandBlocks) gen(rng RNG, makeSet func() func(x, y int)) {
 	r.ensureValid()
-	if rng == nil {
-		rng = defaultRNG
-	}
+	rng = rngOrDefault(rng)
 
 	for x := 0; x < r.W; x += r.BlockW {
 		for y := 0; y < r.H; y += r.BlockH {
@@ -45,16 +40,14 @@ func (r RandBlocks) gen(rng *rand.Rand, makeSet func() func(x, y int)) {
 	}
 }
 
-func (r RandBlocks) Paletted(rng *rand.Rand, palette color.Palette) *image.Paletted {
+func (r RandBlocks) Paletted(rng RNG, palette color.Palette) *image.Paletted {
 	if palette == nil {
 		palette = defaultRandPalette
 	}
-	if rng == nil {
-		rng = defaultRNG
-	}
+	rng = rngOrDefault(rng)
 	var img = image.NewPaletted(image.Rect(0, 0, r.W, r.H), palette)
 	var makeSet = func() func(x, y int) {
-		col := uint8(rng.Intn(len(palette)))
+		col := uint8(rngIntn(rng, len(palette)))
 		return func(x, y int) {
 			img.Pix[r.W*y+x] = col
 		}
@@ -63,13 +56,11 @@ func (r RandBlocks) Paletted(rng *rand.Rand, palette color.Palette) *image.Palet
 	return img
 }
 
-func (r RandBlocks) RGBA(rng *rand.Rand) *image.RGBA {
-	if rng == nil {
-		rng = defaultRNG
-	}
+func (r RandBlocks) RGBA(rng RNG) *image.RGBA {
+	rng = rngOrDefault(rng)
 	var img = image.NewRGBA(image.Rect(0, 0, r.W, r.H))
 	var makeSet = func() func(x, y int) {
-		v := rng.Uint32()
+		v := rngUint32(rng)
 		cr, cg, cb := uint8(v>>16), uint8(v>>8), uint8(v)
 		return func(x, y int) {
 			i := (r.W*y + x) * 4
@@ -83,13 +74,11 @@ func (r RandBlocks) RGBA(rng *rand.Rand) *image.RGBA {
 	return img
 }
 
-func (r RandBlocks) RGBA64(rng *rand.Rand) *image.RGBA64 {
-	if rng == nil {
-		rng = defaultRNG
-	}
+func (r RandBlocks) RGBA64(rng RNG) *image.RGBA64 {
+	rng = rngOrDefault(rng)
 	var img = image.NewRGBA64(image.Rect(0, 0, r.W, r.H))
 	var makeSet = func() func(x, y int) {
-		v := rng.Uint32()
+		v := rngUint32(rng)
 		return func(x, y int) {
 			i := (r.W*y + x) * 8
 			img.Pix[i+0] = uint8(v >> 16)
@@ -106,13 +95,11 @@ func (r RandBlocks) RGBA64(rng *rand.Rand) *image.RGBA64 {
 	return img
 }
 
-func (r RandBlocks) NRGBA(rng *rand.Rand) *image.NRGBA {
-	if rng == nil {
-		rng = defaultRNG
-	}
+func (r RandBlocks) NRGBA(rng RNG) *image.NRGBA {
+	rng = rngOrDefault(rng)
 	var img = image.NewNRGBA(image.Rect(0, 0, r.W, r.H))
 	var makeSet = func() func(x, y int) {
-		v := rng.Uint32()
+		v := rngUint32(rng)
 		cr, cg, cb := uint8(v>>16), uint8(v>>8), uint8(v)
 		return func(x, y int) {
 			i := (r.W*y + x) * 4
@@ -126,13 +113,11 @@ func (r RandBlocks) NRGBA(rng *rand.Rand) *image.NRGBA {
 	return img
 }
 
-func (r RandBlocks) NRGBA64(rng *rand.Rand) *image.NRGBA64 {
-	if rng == nil {
-		rng = defaultRNG
-	}
+func (r RandBlocks) NRGBA64(rng RNG) *image.NRGBA64 {
+	rng = rngOrDefault(rng)
 	var img = image.NewNRGBA64(image.Rect(0, 0, r.W, r.H))
 	var makeSet = func() func(x, y int) {
-		v := rng.Uint32()
+		v := rngUint32(rng)
 		return func(x, y int) {
 			i := (r.W*y + x) * 8
 			img.Pix[i+0] = uint8(v >> 16)
@@ -149,13 +134,11 @@ func (r RandBlocks) NRGBA64(rng *rand.Rand) *image.NRGBA64 {
 	return img
 }
 
-func (r RandBlocks) CMYK(rng *rand.Rand) *image.CMYK {
-	if rng == nil {
-		rng = defaultRNG
-	}
+func (r RandBlocks) CMYK(rng RNG) *image.CMYK {
+	rng = rngOrDefault(rng)
 	var img = image.NewCMYK(image.Rect(0, 0, r.W, r.H))
 	var makeSet = func() func(x, y int) {
-		v := rng.Uint32()
+		v := rngUint32(rng)
 		cc, cm, cy, ck := uint8(v>>24), uint8(v>>16), uint8(v>>8), uint8(v)
 		return func(x, y int) {
 			i := (r.W*y + x) * 4
@@ -169,23 +152,8 @@ func (r RandBlocks) CMYK(rng *rand.Rand) *image.CMYK {
 	return img
 }
 
-func (r RandBlocks) YCbCr(rng *rand.Rand) *image.YCbCr {
-	// No way to create a YCbCr image without lots of spelunking and reading maths. It's
-	// late, and this works. This only produces a 4:2:0 image; ultimately I'll need to
-	// work out how to encode these things from RGB to hit all those bases but not
-	// tonight.
-	img := r.RGBA(rng)
-	var buf bytes.Buffer
-	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
-		panic(err)
-	}
-	enc, err := jpeg.Decode(&buf)
-	if err != nil {
-		panic(err)
-	}
-	ycb, ok := enc.(*image.YCbCr)
-	if !ok {
-		panic("could not produce YCbCr")
-	}
-	return ycb
+// YCbCr produces a 4:2:0 image, which is what most JPEGs use. Wrap RandBlocks in
+// Subsampled for other ratios.
+func (r RandBlocks) YCbCr(rng RNG) *image.YCbCr {
+	return ToYCbCr(r.RGBA(rng), image.YCbCrSubsampleRatio420)
 }
@@ -3,15 +3,14 @@ package testimg
 import (
 	"image"
 	"image/color"
-	"math/rand"
 )
 
 type Recipe interface {
-	RGBA(*rand.Rand) *image.RGBA
-	RGBA64(*rand.Rand) *image.RGBA64
-	NRGBA(*rand.Rand) *image.NRGBA
-	NRGBA64(*rand.Rand) *image.NRGBA64
-	Paletted(*rand.Rand, color.Palette) *image.Paletted
-	YCbCr(*rand.Rand) *image.YCbCr
-	CMYK(*rand.Rand) *image.CMYK
+	RGBA(RNG) *image.RGBA
+	RGBA64(RNG) *image.RGBA64
+	NRGBA(RNG) *image.NRGBA
+	NRGBA64(RNG) *image.NRGBA64
+	Paletted(RNG, color.Palette) *image.Paletted
+	YCbCr(RNG) *image.YCbCr
+	CMYK(RNG) *image.CMYK
 }
@@ -0,0 +1,99 @@
+package testimg
+
+import "reflect"
+
+// RNG is the source of randomness for every generator in testimg. Generators only
+// call Uint64, and derive everything else from it in a fully specified way, so
+// the same RNG stream always produces the same image.
+//
+// Use NewRNG to get one whose stream will never change. *math/rand.Rand also
+// satisfies RNG, but its stream is only as stable as math/rand's.
+//
+// Implementations need not be safe for concurrent use; give each goroutine its own.
+// A nil RNG, or a nil pointer such as a (*rand.Rand)(nil), is treated as NewRNG(0),
+// created afresh for each call, so passing nil is safe from parallel tests.
+//
+type RNG interface {
+	Uint64() uint64
+}
+
+// SplitMix64 is Steele, Lea and Flood's SplitMix64 generator. It is tiny, fast, and
+// good enough for generating test images; it is not suitable for cryptography.
+//
+// The stream for a given seed is part of testimg's API and will not change.
+//
+type SplitMix64 struct {
+	state uint64
+}
+
+var _ RNG = &SplitMix64{}
+
+// NewRNG returns a SplitMix64 seeded with seed.
+func NewRNG(seed uint64) *SplitMix64 {
+	return &SplitMix64{state: seed}
+}
+
+// Seed resets the generator to the start of the stream for seed.
+func (s *SplitMix64) Seed(seed uint64) {
+	s.state = seed
+}
+
+// Uint64 returns the next value in the stream.
+func (s *SplitMix64) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func rngOrDefault(rng RNG) RNG {
+	if rng == nil {
+		return NewRNG(0)
+	}
+	// An interface holding a nil pointer isn't nil, but calling Uint64 on it would
+	// still panic:
+	if v := reflect.ValueOf(rng); v.Kind() == reflect.Ptr && v.IsNil() {
+		return NewRNG(0)
+	}
+	return rng
+}
+
+// rngUint32 returns the high 32 bits of the next value, which are the better mixed
+// half for some generators.
+func rngUint32(rng RNG) uint32 {
+	return uint32(rng.Uint64() >> 32)
+}
+
+// rngFloat64 returns a value in [0, 1) with 53 bits of precision.
+func rngFloat64(rng RNG) float64 {
+	return float64(rng.Uint64()>>11) / (1 << 53)
+}
+
+// rngIntn returns a value in [0, n) without modulo bias, by rejection. n must be
+// positive.
+func rngIntn(rng RNG, n int) int {
+	if n <= 0 {
+		panic("testimg: invalid argument to rngIntn")
+	}
+	un := uint64(n)
+	limit := ^uint64(0) - (^uint64(0)%un+1)%un
+	for {
+		if v := rng.Uint64(); v <= limit {
+			return int(v % un)
+		}
+	}
+}
+
+// rngPerm returns a random permutation of [0, n), using a Fisher-Yates shuffle.
+func rngPerm(rng RNG, n int) []int {
+	p := make([]int, n)
+	for i := range p {
+		p[i] = i
+	}
+	for i := n - 1; i > 0; i-- {
+		j := rngIntn(rng, i+1)
+		p[i], p[j] = p[j], p[i]
+	}
+	return p
+}
@@ -0,0 +1,185 @@
+package testimg
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// RandCircles draws N (default 16) filled circles of random size and colour over
+// an opaque background of random colour. If Alpha is set, the circles are
+// translucent; overlapping circles are composited in the order they were drawn.
+type RandCircles struct {
+	W, H  int
+	N     int
+	Alpha bool
+}
+
+func (r RandCircles) field(rng RNG) (w, h int, f field) {
+	n := r.N
+	if n <= 0 {
+		n = 16
+	}
+	type circle struct {
+		x, y, r2 float64
+		c        color.NRGBA64
+	}
+	bg := color.NRGBA64Model.Convert(randNRGBA(rng, false)).(color.NRGBA64)
+	maxR := math.Max(float64(r.W), float64(r.H)) / 4
+	circles := make([]circle, n)
+	for i := range circles {
+		rad := 1 + rngFloat64(rng)*maxR
+		circles[i] = circle{
+			x:  rngFloat64(rng) * float64(r.W),
+			y:  rngFloat64(rng) * float64(r.H),
+			r2: rad * rad,
+			c:  color.NRGBA64Model.Convert(randNRGBA(rng, r.Alpha)).(color.NRGBA64),
+		}
+	}
+
+	return r.W, r.H, func(x, y int) color.NRGBA64 {
+		// Sample at the pixel centre:
+		px, py := float64(x)+0.5, float64(y)+0.5
+		out := bg
+		for _, c := range circles {
+			dx, dy := px-c.x, py-c.y
+			if dx*dx+dy*dy <= c.r2 {
+				out = over(out, c.c)
+			}
+		}
+		return out
+	}
+}
+
+func (r RandCircles) RGBA(rng RNG) *image.RGBA       { return renderRGBA(r, rng) }
+func (r RandCircles) RGBA64(rng RNG) *image.RGBA64   { return renderRGBA64(r, rng) }
+func (r RandCircles) NRGBA(rng RNG) *image.NRGBA     { return renderNRGBA(r, rng) }
+func (r RandCircles) NRGBA64(rng RNG) *image.NRGBA64 { return renderNRGBA64(r, rng) }
+func (r RandCircles) YCbCr(rng RNG) *image.YCbCr     { return renderYCbCr(r, rng) }
+func (r RandCircles) CMYK(rng RNG) *image.CMYK       { return renderCMYK(r, rng) }
+func (r RandCircles) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return renderPaletted(r, rng, pal)
+}
+
+// RandPolygons draws N (default 16) filled polygons with random vertices over an
+// opaque background of random colour. Each polygon has Sides vertices (default
+// 5), and may be concave or self-intersecting; it is filled using the even-odd
+// rule. If Alpha is set, the polygons are translucent.
+type RandPolygons struct {
+	W, H  int
+	N     int
+	Sides int
+	Alpha bool
+}
+
+func (r RandPolygons) field(rng RNG) (w, h int, f field) {
+	n, sides := r.N, r.Sides
+	if n <= 0 {
+		n = 16
+	}
+	if sides < 3 {
+		sides = 5
+	}
+	type polygon struct {
+		xs, ys []float64
+		bounds [4]float64
+		c      color.NRGBA64
+	}
+	bg := color.NRGBA64Model.Convert(randNRGBA(rng, false)).(color.NRGBA64)
+	maxR := math.Max(float64(r.W), float64(r.H)) / 3
+	polys := make([]polygon, n)
+	for i := range polys {
+		cx, cy := rngFloat64(rng)*float64(r.W), rngFloat64(rng)*float64(r.H)
+		p := polygon{
+			xs:     make([]float64, sides),
+			ys:     make([]float64, sides),
+			bounds: [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)},
+		}
+		for v := 0; v < sides; v++ {
+			rad := (0.2 + rngFloat64(rng)*0.8) * maxR
+			theta := (float64(v) + rngFloat64(rng)*0.5) * 2 * math.Pi / float64(sides)
+			p.xs[v], p.ys[v] = cx+math.Cos(theta)*rad, cy+math.Sin(theta)*rad
+			p.bounds[0], p.bounds[1] = math.Min(p.bounds[0], p.xs[v]), math.Min(p.bounds[1], p.ys[v])
+			p.bounds[2], p.bounds[3] = math.Max(p.bounds[2], p.xs[v]), math.Max(p.bounds[3], p.ys[v])
+		}
+		p.c = color.NRGBA64Model.Convert(randNRGBA(rng, r.Alpha)).(color.NRGBA64)
+		polys[i] = p
+	}
+
+	return r.W, r.H, func(x, y int) color.NRGBA64 {
+		px, py := float64(x)+0.5, float64(y)+0.5
+		out := bg
+		for _, p := range polys {
+			if px < p.bounds[0] || py < p.bounds[1] || px > p.bounds[2] || py > p.bounds[3] {
+				continue
+			}
+			inside := false
+			for i, j := 0, len(p.xs)-1; i < len(p.xs); j, i = i, i+1 {
+				if (p.ys[i] > py) != (p.ys[j] > py) &&
+					px < (p.xs[j]-p.xs[i])*(py-p.ys[i])/(p.ys[j]-p.ys[i])+p.xs[i] {
+					inside = !inside
+				}
+			}
+			if inside {
+				out = over(out, p.c)
+			}
+		}
+		return out
+	}
+}
+
+func (r RandPolygons) RGBA(rng RNG) *image.RGBA       { return renderRGBA(r, rng) }
+func (r RandPolygons) RGBA64(rng RNG) *image.RGBA64   { return renderRGBA64(r, rng) }
+func (r RandPolygons) NRGBA(rng RNG) *image.NRGBA     { return renderNRGBA(r, rng) }
+func (r RandPolygons) NRGBA64(rng RNG) *image.NRGBA64 { return renderNRGBA64(r, rng) }
+func (r RandPolygons) YCbCr(rng RNG) *image.YCbCr     { return renderYCbCr(r, rng) }
+func (r RandPolygons) CMYK(rng RNG) *image.CMYK       { return renderCMYK(r, rng) }
+func (r RandPolygons) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return renderPaletted(r, rng, pal)
+}
+
+// AlphaRamp wraps another Recipe, scaling its alpha channel from 0 at the left
+// edge to fully opaque at the right, or from top to bottom if Vertical is set.
+// Base must not be nil.
+//
+// Image types without an alpha channel (YCbCr and CMYK) receive the ramped image
+// composited over black.
+//
+type AlphaRamp struct {
+	Base     Recipe
+	Vertical bool
+}
+
+func (a AlphaRamp) field(rng RNG) (w, h int, f field) {
+	if a.Base == nil {
+		panic("testimg: AlphaRamp missing Base")
+	}
+	base := a.Base.NRGBA64(rng)
+	b := base.Bounds()
+	span := b.Dx() - 1
+	if a.Vertical {
+		span = b.Dy() - 1
+	}
+	if span <= 0 {
+		span = 1
+	}
+	return b.Dx(), b.Dy(), func(x, y int) color.NRGBA64 {
+		c := base.NRGBA64At(b.Min.X+x, b.Min.Y+y)
+		pos := x
+		if a.Vertical {
+			pos = y
+		}
+		c.A = uint16(uint32(c.A) * uint32(pos) / uint32(span))
+		return c
+	}
+}
+
+func (a AlphaRamp) RGBA(rng RNG) *image.RGBA       { return renderRGBA(a, rng) }
+func (a AlphaRamp) RGBA64(rng RNG) *image.RGBA64   { return renderRGBA64(a, rng) }
+func (a AlphaRamp) NRGBA(rng RNG) *image.NRGBA     { return renderNRGBA(a, rng) }
+func (a AlphaRamp) NRGBA64(rng RNG) *image.NRGBA64 { return renderNRGBA64(a, rng) }
+func (a AlphaRamp) YCbCr(rng RNG) *image.YCbCr     { return renderYCbCr(a, rng) }
+func (a AlphaRamp) CMYK(rng RNG) *image.CMYK       { return renderCMYK(a, rng) }
+func (a AlphaRamp) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return renderPaletted(a, rng, pal)
+}
@@ -0,0 +1,722 @@
+package testimg
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// MaxSpecSize is the largest width, height or padding ParseRecipe accepts.
+	MaxSpecSize = 1 << 13
+
+	// MaxSpecPixels is the largest image area ParseRecipe accepts, including the
+	// padding around a SubImage.
+	MaxSpecPixels = 1 << 24
+
+	// maxSpecOrigin keeps the bounds of a SubImage well away from overflowing.
+	maxSpecOrigin = 1 << 30
+
+	// These keep the time taken to render a recipe within reason; any more makes
+	// no visible difference anyway.
+	maxSpecOctaves = 32
+	maxSpecShapes  = 1 << 10
+	maxSpecSides   = 1 << 8
+)
+
+// RecipeParser builds a Recipe from the arguments of a spec, which are the
+// colon-separated parts after the kind, with any parentheses left intact. Use
+// ParseRecipe to parse nested recipes.
+type RecipeParser func(args []string) (Recipe, error)
+
+var (
+	recipeParsersLock sync.RWMutex
+	recipeParsers     = map[string]RecipeParser{}
+)
+
+// RegisterRecipe makes a kind of recipe available to ParseRecipe. Recipes
+// registered this way should also have a String method that returns a spec
+// ParseRecipe will accept. If RegisterRecipe is called twice with the same kind,
+// or if parse is nil, it panics.
+func RegisterRecipe(kind string, parse RecipeParser) {
+	recipeParsersLock.Lock()
+	defer recipeParsersLock.Unlock()
+
+	if parse == nil {
+		panic("testimg: RegisterRecipe parser is nil")
+	}
+	if kind == "" || strings.ContainsAny(kind, ":()") {
+		panic(fmt.Sprintf("testimg: invalid recipe kind %q", kind))
+	}
+	if _, dupe := recipeParsers[kind]; dupe {
+		panic(fmt.Sprintf("testimg: RegisterRecipe called twice for kind %q", kind))
+	}
+	recipeParsers[kind] = parse
+}
+
+// RecipeKinds returns the sorted names of every registered kind of recipe.
+func RecipeKinds() []string {
+	recipeParsersLock.RLock()
+	defer recipeParsersLock.RUnlock()
+
+	kinds := make([]string, 0, len(recipeParsers))
+	for kind := range recipeParsers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// ParseRecipe parses a recipe spec, which is a textual form of a Recipe, so that
+// recipes can come from flags, environment variables or files. A spec is a kind,
+// followed by colon-separated arguments:
+//
+//	randblocks:512x512:32x32
+//	perlin:256x256:octaves=4:mono
+//	alpharamp:(plasma:64x64):vertical
+//
+// Arguments are either positional, like the image size, a bare flag, like "mono",
+// or key=value pairs. A nested recipe is written as a spec in parentheses.
+//
+// Every Recipe in this package has a String method that returns its spec, so the
+// two round-trip.
+//
+// The built-in kinds are:
+//
+//	randblocks:WxH[:BWxBH]
+//	lineargradient:WxH[:from=#rrggbb[aa]][:to=#rrggbb[aa]][:angle=DEG]
+//	radialgradient:WxH[:inner=#rrggbb[aa]][:outer=#rrggbb[aa]]
+//	checkerboard:WxH[:CWxCH][:a=#rrggbb[aa]][:b=#rrggbb[aa]]
+//	whitenoise:WxH[:mono][:alpha]
+//	valuenoise:WxH[:scale=N][:octaves=N][:mono]
+//	perlin:WxH[:scale=N][:octaves=N][:mono][:simplex]
+//	simplex:WxH[...]             same as perlin with simplex set
+//	plasma:WxH
+//	circles:WxH[:n=N][:alpha]
+//	polygons:WxH[:n=N][:sides=N][:alpha]
+//	alpharamp:(SPEC)[:vertical]
+//	subsampled:(SPEC):RATIO      RATIO is one of 444, 422, 420, 440, 411, 410
+//	subimage:(SPEC)[:origin=X,Y][:pad=N]
+//	fuzz[:data=HEX]
+//
+// Sizes may be at most MaxSpecSize on each side, and MaxSpecPixels in total
+// including any padding, and the numbers of octaves, shapes and sides are limited
+// too, so a spec from the command line can't ask for an unreasonable amount of
+// memory or time. A nested recipe must not produce an empty image, which fuzz can.
+//
+// Use RegisterRecipe to add more.
+//
+func ParseRecipe(spec string) (Recipe, error) {
+	parts, err := splitSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	recipeParsersLock.RLock()
+	parse := recipeParsers[parts[0]]
+	recipeParsersLock.RUnlock()
+
+	if parse == nil {
+		return nil, fmt.Errorf("testimg: unknown recipe kind %q in %q", parts[0], spec)
+	}
+	r, err := parse(parts[1:])
+	if err != nil {
+		return nil, fmt.Errorf("testimg: invalid recipe %q: %v", spec, err)
+	}
+	return r, nil
+}
+
+// MustParseRecipe is like ParseRecipe, but panics on error. It is intended for
+// tables of test cases.
+func MustParseRecipe(spec string) Recipe {
+	r, err := ParseRecipe(spec)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// splitSpec splits spec at every colon that is not inside parentheses.
+func splitSpec(spec string) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(spec); i++ {
+		switch spec[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("testimg: unbalanced parentheses in recipe %q", spec)
+			}
+		case ':':
+			if depth == 0 {
+				parts = append(parts, spec[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("testimg: unbalanced parentheses in recipe %q", spec)
+	}
+	parts = append(parts, spec[start:])
+	if parts[0] == "" {
+		return nil, fmt.Errorf("testimg: missing kind in recipe %q", spec)
+	}
+	return parts, nil
+}
+
+// specArgs helps the built-in parsers consume the arguments of a spec. The first
+// error is kept, and reported by done, so the parsers don't need to check after
+// every argument.
+type specArgs struct {
+	pos   []string
+	named map[string]string
+	err   error
+}
+
+func newSpecArgs(args []string) *specArgs {
+	a := &specArgs{named: map[string]string{}}
+	for _, arg := range args {
+		if i := strings.IndexByte(arg, '='); i >= 0 && !strings.HasPrefix(arg, "(") {
+			key := arg[:i]
+			if _, dupe := a.named[key]; dupe {
+				a.fail(fmt.Errorf("duplicate argument %q", key))
+			}
+			a.named[key] = arg[i+1:]
+		} else {
+			a.pos = append(a.pos, arg)
+		}
+	}
+	return a
+}
+
+func (a *specArgs) fail(err error) {
+	if a.err == nil {
+		a.err = err
+	}
+}
+
+// next consumes the next positional argument, if there is one.
+func (a *specArgs) next() (string, bool) {
+	if len(a.pos) == 0 {
+		return "", false
+	}
+	v := a.pos[0]
+	a.pos = a.pos[1:]
+	return v, true
+}
+
+// size consumes a required positive WxH image size.
+func (a *specArgs) size() (w, h int) {
+	s, ok := a.next()
+	if !ok {
+		a.fail(fmt.Errorf("missing size"))
+		return 0, 0
+	}
+	w, h = a.parseSize(s)
+	if a.err == nil && (w <= 0 || h <= 0) {
+		a.fail(fmt.Errorf("invalid size %q", s))
+	}
+	if a.err == nil && w*h > MaxSpecPixels {
+		a.fail(fmt.Errorf("size %q is larger than %d pixels", s, MaxSpecPixels))
+	}
+	return w, h
+}
+
+// optSize consumes an optional non-negative WxH, if the next positional argument
+// looks like one.
+func (a *specArgs) optSize() (w, h int) {
+	if len(a.pos) == 0 || !strings.Contains(a.pos[0], "x") {
+		return 0, 0
+	}
+	s, _ := a.next()
+	w, h = a.parseSize(s)
+	if a.err == nil && (w < 0 || h < 0) {
+		a.fail(fmt.Errorf("invalid size %q", s))
+	}
+	return w, h
+}
+
+func (a *specArgs) parseSize(s string) (w, h int) {
+	parts := strings.Split(s, "x")
+	if len(parts) == 2 {
+		var err error
+		w, err = strconv.Atoi(parts[0])
+		if err == nil {
+			h, err = strconv.Atoi(parts[1])
+		}
+		if err == nil && (w > MaxSpecSize || h > MaxSpecSize) {
+			a.fail(fmt.Errorf("size %q is larger than %d on a side", s, MaxSpecSize))
+			return 0, 0
+		}
+		if err == nil {
+			return w, h
+		}
+	}
+	a.fail(fmt.Errorf("invalid size %q", s))
+	return 0, 0
+}
+
+// flag consumes a bare flag, or a key=true/false pair.
+func (a *specArgs) flag(key string) bool {
+	for i, v := range a.pos {
+		if v == key {
+			a.pos = append(a.pos[:i:i], a.pos[i+1:]...)
+			return true
+		}
+	}
+	v, ok := a.named[key]
+	if !ok {
+		return false
+	}
+	delete(a.named, key)
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		a.fail(fmt.Errorf("invalid %s %q", key, v))
+	}
+	return b
+}
+
+// int consumes an optional integer of at most max.
+func (a *specArgs) int(key string, max int) int {
+	v, ok := a.named[key]
+	if !ok {
+		return 0
+	}
+	delete(a.named, key)
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		a.fail(fmt.Errorf("invalid %s %q", key, v))
+	} else if n > max {
+		a.fail(fmt.Errorf("%s %d is larger than %d", key, n, max))
+	}
+	return n
+}
+
+func (a *specArgs) float(key string) float64 {
+	v, ok := a.named[key]
+	if !ok {
+		return 0
+	}
+	delete(a.named, key)
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		a.fail(fmt.Errorf("invalid %s %q", key, v))
+	}
+	return f
+}
+
+func (a *specArgs) color(key string) color.NRGBA {
+	v, ok := a.named[key]
+	if !ok {
+		return color.NRGBA{}
+	}
+	delete(a.named, key)
+	c, err := parseNRGBAHex(v)
+	if err != nil {
+		a.fail(fmt.Errorf("invalid %s %q", key, v))
+	}
+	return c
+}
+
+func (a *specArgs) point(key string) image.Point {
+	v, ok := a.named[key]
+	if !ok {
+		return image.Point{}
+	}
+	delete(a.named, key)
+	var p image.Point
+	parts := strings.Split(v, ",")
+	if len(parts) == 2 {
+		var err error
+		p.X, err = strconv.Atoi(parts[0])
+		if err == nil {
+			p.Y, err = strconv.Atoi(parts[1])
+		}
+		if err == nil {
+			return p
+		}
+	}
+	a.fail(fmt.Errorf("invalid %s %q", key, v))
+	return p
+}
+
+func (a *specArgs) bytes(key string) []byte {
+	v, ok := a.named[key]
+	if !ok {
+		return nil
+	}
+	delete(a.named, key)
+	b, err := hex.DecodeString(v)
+	if err != nil {
+		a.fail(fmt.Errorf("invalid %s %q", key, v))
+	}
+	return b
+}
+
+// recipe consumes a required positional nested recipe in parentheses.
+func (a *specArgs) recipe() Recipe {
+	s, ok := a.next()
+	if !ok || len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		a.fail(fmt.Errorf("missing recipe in parentheses"))
+		return nil
+	}
+	r, err := ParseRecipe(s[1 : len(s)-1])
+	if err != nil {
+		a.fail(err)
+	} else if size, ok := recipeSize(r); ok && (size.X <= 0 || size.Y <= 0) {
+		a.fail(fmt.Errorf("nested recipe %q produces an empty image", s))
+	}
+	return r
+}
+
+// recipeSize returns the size of the images r produces, if it is known. The
+// built-in recipes that wrap another one, like AlphaRamp, have the size of their
+// base.
+func recipeSize(r Recipe) (size image.Point, ok bool) {
+	switch r := r.(type) {
+	case RandBlocks:
+		return image.Pt(r.W, r.H), true
+	case LinearGradient:
+		return image.Pt(r.W, r.H), true
+	case RadialGradient:
+		return image.Pt(r.W, r.H), true
+	case Checkerboard:
+		return image.Pt(r.W, r.H), true
+	case WhiteNoise:
+		return image.Pt(r.W, r.H), true
+	case ValueNoise:
+		return image.Pt(r.W, r.H), true
+	case PerlinNoise:
+		return image.Pt(r.W, r.H), true
+	case Plasma:
+		return image.Pt(r.W, r.H), true
+	case RandCircles:
+		return image.Pt(r.W, r.H), true
+	case RandPolygons:
+		return image.Pt(r.W, r.H), true
+	case FuzzImage:
+		return r.size(), true
+	case AlphaRamp:
+		return recipeSize(r.Base)
+	case Subsampled:
+		return recipeSize(r.Base)
+	case SubImage:
+		return recipeSize(r.Base)
+	}
+	return size, false
+}
+
+// done returns the first error, or an error if any arguments were not consumed.
+func (a *specArgs) done() error {
+	if a.err != nil {
+		return a.err
+	}
+	if len(a.pos) > 0 {
+		return fmt.Errorf("unexpected argument %q", a.pos[0])
+	}
+	for key := range a.named {
+		return fmt.Errorf("unknown argument %q", key)
+	}
+	return nil
+}
+
+// specWriter builds the spec returned by a recipe's String method. Arguments with
+// zero values are left out, as the parsers treat missing arguments as zero.
+type specWriter struct {
+	buf strings.Builder
+}
+
+func newSpecWriter(kind string) *specWriter {
+	s := &specWriter{}
+	s.buf.WriteString(kind)
+	return s
+}
+
+func (s *specWriter) arg(v string) *specWriter {
+	s.buf.WriteByte(':')
+	s.buf.WriteString(v)
+	return s
+}
+
+func (s *specWriter) size(w, h int) *specWriter {
+	return s.arg(fmt.Sprintf("%dx%d", w, h))
+}
+
+func (s *specWriter) optSize(w, h int) *specWriter {
+	if w == 0 && h == 0 {
+		return s
+	}
+	return s.size(w, h)
+}
+
+func (s *specWriter) flag(key string, v bool) *specWriter {
+	if !v {
+		return s
+	}
+	return s.arg(key)
+}
+
+func (s *specWriter) int(key string, v int) *specWriter {
+	if v == 0 {
+		return s
+	}
+	return s.arg(key + "=" + strconv.Itoa(v))
+}
+
+func (s *specWriter) float(key string, v float64) *specWriter {
+	if v == 0 {
+		return s
+	}
+	return s.arg(key + "=" + strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+func (s *specWriter) color(key string, c color.NRGBA) *specWriter {
+	if c == (color.NRGBA{}) {
+		return s
+	}
+	if c.A == 0xff {
+		return s.arg(fmt.Sprintf("%s=#%02x%02x%02x", key, c.R, c.G, c.B))
+	}
+	return s.arg(fmt.Sprintf("%s=#%02x%02x%02x%02x", key, c.R, c.G, c.B, c.A))
+}
+
+func (s *specWriter) point(key string, p image.Point) *specWriter {
+	if p == (image.Point{}) {
+		return s
+	}
+	return s.arg(fmt.Sprintf("%s=%d,%d", key, p.X, p.Y))
+}
+
+func (s *specWriter) bytes(key string, b []byte) *specWriter {
+	if len(b) == 0 {
+		return s
+	}
+	return s.arg(key + "=" + hex.EncodeToString(b))
+}
+
+// recipe writes a nested recipe. Recipes without a String method can't be
+// written as a spec, so they are written as their type, which ParseRecipe will
+// reject.
+func (s *specWriter) recipe(r Recipe) *specWriter {
+	if sr, ok := r.(fmt.Stringer); ok {
+		return s.arg("(" + sr.String() + ")")
+	}
+	return s.arg(fmt.Sprintf("(%T)", r))
+}
+
+func (s *specWriter) String() string {
+	return s.buf.String()
+}
+
+var ycbcrRatioNames = map[image.YCbCrSubsampleRatio]string{
+	image.YCbCrSubsampleRatio444: "444",
+	image.YCbCrSubsampleRatio422: "422",
+	image.YCbCrSubsampleRatio420: "420",
+	image.YCbCrSubsampleRatio440: "440",
+	image.YCbCrSubsampleRatio411: "411",
+	image.YCbCrSubsampleRatio410: "410",
+}
+
+func init() {
+	RegisterRecipe("randblocks", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r RandBlocks
+		r.W, r.H = a.size()
+		r.BlockW, r.BlockH = a.optSize()
+		return r, a.done()
+	})
+
+	RegisterRecipe("lineargradient", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r LinearGradient
+		r.W, r.H = a.size()
+		r.From, r.To, r.Angle = a.color("from"), a.color("to"), a.float("angle")
+		return r, a.done()
+	})
+
+	RegisterRecipe("radialgradient", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r RadialGradient
+		r.W, r.H = a.size()
+		r.Inner, r.Outer = a.color("inner"), a.color("outer")
+		return r, a.done()
+	})
+
+	RegisterRecipe("checkerboard", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r Checkerboard
+		r.W, r.H = a.size()
+		r.CellW, r.CellH = a.optSize()
+		r.A, r.B = a.color("a"), a.color("b")
+		return r, a.done()
+	})
+
+	RegisterRecipe("whitenoise", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r WhiteNoise
+		r.W, r.H = a.size()
+		r.Mono, r.Alpha = a.flag("mono"), a.flag("alpha")
+		return r, a.done()
+	})
+
+	RegisterRecipe("valuenoise", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r ValueNoise
+		r.W, r.H = a.size()
+		r.Scale, r.Octaves, r.Mono = a.int("scale", MaxSpecSize), a.int("octaves", maxSpecOctaves), a.flag("mono")
+		return r, a.done()
+	})
+
+	parsePerlin := func(simplex bool) RecipeParser {
+		return func(args []string) (Recipe, error) {
+			a := newSpecArgs(args)
+			var r PerlinNoise
+			r.W, r.H = a.size()
+			r.Scale, r.Octaves, r.Mono = a.int("scale", MaxSpecSize), a.int("octaves", maxSpecOctaves), a.flag("mono")
+			r.Simplex = a.flag("simplex") || simplex
+			return r, a.done()
+		}
+	}
+	RegisterRecipe("perlin", parsePerlin(false))
+	RegisterRecipe("simplex", parsePerlin(true))
+
+	RegisterRecipe("plasma", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r Plasma
+		r.W, r.H = a.size()
+		return r, a.done()
+	})
+
+	RegisterRecipe("circles", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r RandCircles
+		r.W, r.H = a.size()
+		r.N, r.Alpha = a.int("n", maxSpecShapes), a.flag("alpha")
+		return r, a.done()
+	})
+
+	RegisterRecipe("polygons", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r RandPolygons
+		r.W, r.H = a.size()
+		r.N, r.Sides, r.Alpha = a.int("n", maxSpecShapes), a.int("sides", maxSpecSides), a.flag("alpha")
+		return r, a.done()
+	})
+
+	RegisterRecipe("alpharamp", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r AlphaRamp
+		r.Base = a.recipe()
+		r.Vertical = a.flag("vertical")
+		return r, a.done()
+	})
+
+	RegisterRecipe("subsampled", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r Subsampled
+		r.Base = a.recipe()
+		name, _ := a.next()
+		found := false
+		for ratio, n := range ycbcrRatioNames {
+			if n == name {
+				r.Ratio, found = ratio, true
+			}
+		}
+		if !found {
+			a.fail(fmt.Errorf("invalid subsample ratio %q", name))
+		}
+		return r, a.done()
+	})
+
+	RegisterRecipe("subimage", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r SubImage
+		r.Base = a.recipe()
+		r.Origin, r.Pad = a.point("origin"), a.int("pad", MaxSpecSize)
+		if size, ok := recipeSize(r.Base); ok && a.err == nil {
+			if canvas, _ := r.layout(image.Rectangle{Max: size}); canvas.Dx()*canvas.Dy() > MaxSpecPixels {
+				a.fail(fmt.Errorf("padded size %dx%d is larger than %d pixels", canvas.Dx(), canvas.Dy(), MaxSpecPixels))
+			}
+		}
+		if !r.Origin.In(image.Rect(-maxSpecOrigin, -maxSpecOrigin, maxSpecOrigin+1, maxSpecOrigin+1)) {
+			a.fail(fmt.Errorf("origin %v is further than %d from zero", r.Origin, maxSpecOrigin))
+		}
+		return r, a.done()
+	})
+
+	RegisterRecipe("fuzz", func(args []string) (Recipe, error) {
+		a := newSpecArgs(args)
+		var r FuzzImage
+		r.Data = a.bytes("data")
+		return r, a.done()
+	})
+}
+
+func (r RandBlocks) String() string {
+	return newSpecWriter("randblocks").size(r.W, r.H).optSize(r.BlockW, r.BlockH).String()
+}
+
+func (r LinearGradient) String() string {
+	return newSpecWriter("lineargradient").size(r.W, r.H).
+		color("from", r.From).color("to", r.To).float("angle", r.Angle).String()
+}
+
+func (r RadialGradient) String() string {
+	return newSpecWriter("radialgradient").size(r.W, r.H).
+		color("inner", r.Inner).color("outer", r.Outer).String()
+}
+
+func (r Checkerboard) String() string {
+	return newSpecWriter("checkerboard").size(r.W, r.H).optSize(r.CellW, r.CellH).
+		color("a", r.A).color("b", r.B).String()
+}
+
+func (r WhiteNoise) String() string {
+	return newSpecWriter("whitenoise").size(r.W, r.H).flag("mono", r.Mono).flag("alpha", r.Alpha).String()
+}
+
+func (r ValueNoise) String() string {
+	return newSpecWriter("valuenoise").size(r.W, r.H).
+		int("scale", r.Scale).int("octaves", r.Octaves).flag("mono", r.Mono).String()
+}
+
+func (r PerlinNoise) String() string {
+	return newSpecWriter("perlin").size(r.W, r.H).
+		int("scale", r.Scale).int("octaves", r.Octaves).flag("mono", r.Mono).flag("simplex", r.Simplex).String()
+}
+
+func (r Plasma) String() string {
+	return newSpecWriter("plasma").size(r.W, r.H).String()
+}
+
+func (r RandCircles) String() string {
+	return newSpecWriter("circles").size(r.W, r.H).int("n", r.N).flag("alpha", r.Alpha).String()
+}
+
+func (r RandPolygons) String() string {
+	return newSpecWriter("polygons").size(r.W, r.H).
+		int("n", r.N).int("sides", r.Sides).flag("alpha", r.Alpha).String()
+}
+
+func (a AlphaRamp) String() string {
+	return newSpecWriter("alpharamp").recipe(a.Base).flag("vertical", a.Vertical).String()
+}
+
+func (s Subsampled) String() string {
+	return newSpecWriter("subsampled").recipe(s.Base).arg(ycbcrRatioNames[s.Ratio]).String()
+}
+
+func (s SubImage) String() string {
+	return newSpecWriter("subimage").recipe(s.Base).point("origin", s.Origin).int("pad", s.Pad).String()
+}
+
+func (r FuzzImage) String() string {
+	return newSpecWriter("fuzz").bytes("data", r.Data).String()
+}
@@ -0,0 +1,117 @@
+package testimg
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+// solidRecipe is registered to check that recipe kinds from outside the built-in
+// set work with ParseRecipe.
+type solidRecipe struct {
+	Checkerboard
+}
+
+func (s solidRecipe) String() string {
+	return fmt.Sprintf("testsolid:%dx%d", s.W, s.H)
+}
+
+func init() {
+	RegisterRecipe("testsolid", func(args []string) (Recipe, error) {
+		var s solidRecipe
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected size")
+		}
+		if _, err := fmt.Sscanf(args[0], "%dx%d", &s.W, &s.H); err != nil {
+			return nil, err
+		}
+		s.A = color.NRGBA{0x12, 0x34, 0x56, 0xff}
+		s.B = s.A
+		return s, nil
+	})
+}
+
+func TestRecipeSpec(t *testing.T) {
+	for _, r := range []Recipe{
+		RandBlocks{W: 512, H: 512, BlockW: 32, BlockH: 32},
+		RandBlocks{W: 3, H: 4},
+		LinearGradient{W: 5, H: 6, From: color.NRGBA{1, 2, 3, 4}, To: color.NRGBA{5, 6, 7, 0xff}, Angle: 12.5},
+		RadialGradient{W: 11, H: 13},
+		Checkerboard{W: 7, H: 8, A: color.NRGBA{0xff, 0, 0, 0xff}},
+		Checkerboard{W: 11, H: 13, CellW: 3, CellH: 5},
+		WhiteNoise{W: 9, H: 10, Mono: true, Alpha: true},
+		ValueNoise{W: 11, H: 13, Scale: 16, Octaves: 3},
+		PerlinNoise{W: 11, H: 13, Octaves: 4},
+		PerlinNoise{W: 11, H: 13, Octaves: 4, Simplex: true},
+		Plasma{W: 11, H: 13},
+		RandCircles{W: 11, H: 13, Alpha: true},
+		RandPolygons{W: 11, H: 13, Sides: 7},
+		AlphaRamp{Base: Plasma{W: 11, H: 13}},
+		Subsampled{Base: RandBlocks{W: 16, H: 16, BlockW: 4, BlockH: 2}, Ratio: image.YCbCrSubsampleRatio410},
+		SubImage{Base: AlphaRamp{Base: Plasma{W: 8, H: 8}}, Origin: image.Pt(-3, 5), Pad: 2},
+		SubImage{Base: FuzzImage{Data: []byte{1, 1}}},
+		FuzzImage{Data: []byte{1, 2, 0xff}},
+		FuzzImage{},
+		solidRecipe{Checkerboard{W: 4, H: 4, A: color.NRGBA{0x12, 0x34, 0x56, 0xff}, B: color.NRGBA{0x12, 0x34, 0x56, 0xff}}},
+	} {
+		spec := fmt.Sprint(r)
+		t.Run(spec, func(t *testing.T) {
+			parsed, err := ParseRecipe(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(parsed, r) {
+				t.Fatalf("expected %#v, found %#v", r, parsed)
+			}
+		})
+	}
+
+	for spec, want := range map[string]Recipe{
+		"randblocks:512x512:32x32":          RandBlocks{W: 512, H: 512, BlockW: 32, BlockH: 32},
+		"simplex:64x32:octaves=4:mono":      PerlinNoise{W: 64, H: 32, Octaves: 4, Mono: true, Simplex: true},
+		"whitenoise:2x2:mono=false:alpha":   WhiteNoise{W: 2, H: 2, Alpha: true},
+		"alpharamp:(plasma:4x4):vertical":   AlphaRamp{Base: Plasma{W: 4, H: 4}, Vertical: true},
+		"subsampled:(circles:4x4:n=3):420":  Subsampled{Base: RandCircles{W: 4, H: 4, N: 3}, Ratio: image.YCbCrSubsampleRatio420},
+		"plasma:8192x2048":                  Plasma{W: MaxSpecSize, H: MaxSpecPixels / MaxSpecSize},
+		"subimage:(plasma:4094x4094):pad=1": SubImage{Base: Plasma{W: 4094, H: 4094}, Pad: 1},
+	} {
+		if r := MustParseRecipe(spec); !reflect.DeepEqual(r, want) {
+			t.Fatalf("%s: expected %#v, found %#v", spec, want, r)
+		}
+	}
+
+	// Every image type renders for a negative origin, including subsampled YCbCr:
+	for _, ratio := range []string{"444", "422", "420", "440", "411", "410"} {
+		r := MustParseRecipe("subimage:(subsampled:(plasma:5x7):" + ratio + "):origin=-5,-5")
+		r.RGBA(nil)
+		r.RGBA64(nil)
+		r.NRGBA(nil)
+		r.NRGBA64(nil)
+		r.YCbCr(nil)
+		r.CMYK(nil)
+		r.Paletted(nil, nil)
+	}
+
+	for _, spec := range []string{
+		"", "nope:1x1", "plasma", "plasma:0x1", "plasma:1x1:extra", "plasma:1x1:n=2",
+		"circles:1x1:n=x", "alpharamp:plasma:1x1", "alpharamp:(plasma:1x1", "subsampled:(plasma:1x1):421",
+		"lineargradient:1x1:from=red", "randblocks:1x1:2", "circles:1x1:n=1:n=2",
+
+		// Too large:
+		"plasma:8193x1", "plasma:1x8193", "plasma:8192x8192", "randblocks:1x1:8193x1",
+		"plasma:99999999999999999999x1", "subimage:(plasma:1x1):pad=8193",
+		"subimage:(plasma:1x1):origin=2000000000,0", "subimage:(randblocks:4096x4096):pad=8192",
+		"subimage:(randblocks:4096x4096):pad=1", "subimage:(alpharamp:(plasma:4096x4096))",
+		"perlin:4x4:octaves=33", "circles:4x4:n=1025", "polygons:4x4:sides=257", "polygons:4x4:n=1025",
+
+		// Nested recipes that would render an empty image:
+		"alpharamp:(fuzz)", "alpharamp:(fuzz:data=ff)", "subsampled:(fuzz:data=4100):420",
+		"alpharamp:(subimage:(fuzz:data=0100))",
+	} {
+		if _, err := ParseRecipe(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package testimg
+
+import (
+	"image"
+	"image/color"
+)
+
+// SubImage wraps another Recipe so that every image it produces has the same
+// content as Base's, but is a sub-image of a larger canvas. The result's
+// Bounds().Min is Origin, and its stride is padded by Pad pixels on either side.
+// This catches consumers that assume Bounds().Min is (0, 0) or that rows are
+// tightly packed.
+//
+// If both Origin and Pad are zero, an Origin of (3, 5) and a Pad of 7 are used.
+//
+// The rest of the canvas is filled with noise, so any pixels read from outside the
+// sub-image will not match. The noise does not use the rng passed to each method,
+// so the content is the same as Base's for the same rng.
+//
+// The YCbCr chroma samples of the canvas are aligned to the canvas, not the
+// sub-image, so unless Origin is a multiple of the chroma sample size, chroma is
+// resampled at the new alignment; the At method of the result is then the expected
+// colour, not Base's. image.YCbCr doesn't work at negative coordinates, so YCbCr
+// images are moved right and down until no part of the canvas is at one, and their
+// Bounds().Min may not be Origin.
+//
+type SubImage struct {
+	Base   Recipe
+	Origin image.Point
+	Pad    int
+}
+
+func (s SubImage) layout(src image.Rectangle) (canvas, sub image.Rectangle) {
+	origin, pad := s.Origin, s.Pad
+	if origin == (image.Point{}) && pad == 0 {
+		origin, pad = image.Pt(3, 5), 7
+	}
+	if pad < 0 {
+		pad = 0
+	}
+	sub = image.Rectangle{Min: origin, Max: origin.Add(src.Size())}
+	canvas = image.Rect(sub.Min.X-pad, sub.Min.Y-pad, sub.Max.X+pad, sub.Max.Y+pad)
+	return canvas, sub
+}
+
+// copyPix copies the pixels in src (tightly packed or not) into the same-sized
+// region of dst starting at dstOff, for an image type with bpp bytes per pixel.
+func copyPix(dst []uint8, dstOff, dstStride int, src []uint8, srcOff, srcStride int, w, h, bpp int) {
+	for y := 0; y < h; y++ {
+		copy(dst[dstOff+y*dstStride:dstOff+y*dstStride+w*bpp], src[srcOff+y*srcStride:])
+	}
+}
+
+func fillNoise(pix []uint8) {
+	rng := NewRNG(1)
+	for i := range pix {
+		pix[i] = uint8(rng.Uint64() >> 56)
+	}
+}
+
+func (s SubImage) RGBA(rng RNG) *image.RGBA {
+	src := s.Base.RGBA(rng)
+	cr, sr := s.layout(src.Bounds())
+	canvas := image.NewRGBA(cr)
+	fillNoise(canvas.Pix)
+	for i := 0; i < len(canvas.Pix); i += 4 {
+		// Keep the noise premultiplied so it's valid:
+		a := canvas.Pix[i+3]
+		canvas.Pix[i], canvas.Pix[i+1], canvas.Pix[i+2] = canvas.Pix[i]&a, canvas.Pix[i+1]&a, canvas.Pix[i+2]&a
+	}
+	copyPix(canvas.Pix, canvas.PixOffset(sr.Min.X, sr.Min.Y), canvas.Stride,
+		src.Pix, src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y), src.Stride, sr.Dx(), sr.Dy(), 4)
+	return canvas.SubImage(sr).(*image.RGBA)
+}
+
+func (s SubImage) RGBA64(rng RNG) *image.RGBA64 {
+	src := s.Base.RGBA64(rng)
+	cr, sr := s.layout(src.Bounds())
+	canvas := image.NewRGBA64(cr)
+	fillNoise(canvas.Pix)
+	for i := 0; i < len(canvas.Pix); i += 8 {
+		a := canvas.Pix[i+6]
+		for c := 0; c < 6; c += 2 {
+			canvas.Pix[i+c] &= a
+		}
+	}
+	copyPix(canvas.Pix, canvas.PixOffset(sr.Min.X, sr.Min.Y), canvas.Stride,
+		src.Pix, src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y), src.Stride, sr.Dx(), sr.Dy(), 8)
+	return canvas.SubImage(sr).(*image.RGBA64)
+}
+
+func (s SubImage) NRGBA(rng RNG) *image.NRGBA {
+	src := s.Base.NRGBA(rng)
+	cr, sr := s.layout(src.Bounds())
+	canvas := image.NewNRGBA(cr)
+	fillNoise(canvas.Pix)
+	copyPix(canvas.Pix, canvas.PixOffset(sr.Min.X, sr.Min.Y), canvas.Stride,
+		src.Pix, src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y), src.Stride, sr.Dx(), sr.Dy(), 4)
+	return canvas.SubImage(sr).(*image.NRGBA)
+}
+
+func (s SubImage) NRGBA64(rng RNG) *image.NRGBA64 {
+	src := s.Base.NRGBA64(rng)
+	cr, sr := s.layout(src.Bounds())
+	canvas := image.NewNRGBA64(cr)
+	fillNoise(canvas.Pix)
+	copyPix(canvas.Pix, canvas.PixOffset(sr.Min.X, sr.Min.Y), canvas.Stride,
+		src.Pix, src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y), src.Stride, sr.Dx(), sr.Dy(), 8)
+	return canvas.SubImage(sr).(*image.NRGBA64)
+}
+
+func (s SubImage) CMYK(rng RNG) *image.CMYK {
+	src := s.Base.CMYK(rng)
+	cr, sr := s.layout(src.Bounds())
+	canvas := image.NewCMYK(cr)
+	fillNoise(canvas.Pix)
+	copyPix(canvas.Pix, canvas.PixOffset(sr.Min.X, sr.Min.Y), canvas.Stride,
+		src.Pix, src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y), src.Stride, sr.Dx(), sr.Dy(), 4)
+	return canvas.SubImage(sr).(*image.CMYK)
+}
+
+func (s SubImage) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	src := s.Base.Paletted(rng, pal)
+	cr, sr := s.layout(src.Bounds())
+	canvas := image.NewPaletted(cr, src.Palette)
+	fillNoise(canvas.Pix)
+	for i, v := range canvas.Pix {
+		canvas.Pix[i] = uint8(int(v) % len(src.Palette))
+	}
+	copyPix(canvas.Pix, canvas.PixOffset(sr.Min.X, sr.Min.Y), canvas.Stride,
+		src.Pix, src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y), src.Stride, sr.Dx(), sr.Dy(), 1)
+	return canvas.SubImage(sr).(*image.Paletted)
+}
+
+func (s SubImage) YCbCr(rng RNG) *image.YCbCr {
+	src := s.Base.YCbCr(rng)
+	sb := src.Bounds()
+	cr, sr := ycbcrNonNegative(s.layout(sb))
+	canvas := image.NewYCbCr(cr, src.SubsampleRatio)
+	fillNoise(canvas.Y)
+	fillNoise(canvas.Cb)
+	fillNoise(canvas.Cr)
+
+	type sum struct{ cb, cr, n int }
+	sums := make([]sum, len(canvas.Cb))
+
+	for y := 0; y < sr.Dy(); y++ {
+		for x := 0; x < sr.Dx(); x++ {
+			cx, cy := sr.Min.X+x, sr.Min.Y+y
+			canvas.Y[canvas.YOffset(cx, cy)] = src.Y[src.YOffset(sb.Min.X+x, sb.Min.Y+y)]
+
+			si := src.COffset(sb.Min.X+x, sb.Min.Y+y)
+			c := &sums[canvas.COffset(cx, cy)]
+			c.cb += int(src.Cb[si])
+			c.cr += int(src.Cr[si])
+			c.n++
+		}
+	}
+	for i, c := range sums {
+		if c.n > 0 {
+			canvas.Cb[i] = uint8((c.cb + c.n/2) / c.n)
+			canvas.Cr[i] = uint8((c.cr + c.n/2) / c.n)
+		}
+	}
+	return canvas.SubImage(sr).(*image.YCbCr)
+}
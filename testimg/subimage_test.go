@@ -0,0 +1,40 @@
+package testimg
+
+import (
+	"fmt"
+	"image"
+	"testing"
+)
+
+func TestSubImageYCbCrNegativeOrigin(t *testing.T) {
+	for _, ratio := range YCbCrSubsampleRatios {
+		for _, size := range []image.Point{{1, 1}, {5, 7}, {8, 8}} {
+			base := Subsampled{Base: Plasma{W: size.X, H: size.Y}, Ratio: ratio}
+			src := base.YCbCr(nil)
+			for oy := -9; oy <= 9; oy += 2 {
+				for ox := -9; ox <= 9; ox++ {
+					for pad := 0; pad <= 8; pad += 3 {
+						s := SubImage{Base: base, Origin: image.Pt(ox, oy), Pad: pad}
+						name := fmt.Sprintf("%v/%v", s, ratio)
+
+						img := s.YCbCr(nil)
+						b := img.Bounds()
+						if b.Size() != size || b.Min.X < 0 || b.Min.Y < 0 {
+							t.Fatalf("%s: unexpected bounds %v", name, b)
+						}
+						for y := 0; y < size.Y; y++ {
+							for x := 0; x < size.X; x++ {
+								if found, want := img.Y[img.YOffset(b.Min.X+x, b.Min.Y+y)], src.Y[src.YOffset(x, y)]; found != want {
+									t.Fatalf("%s: luma at %d,%d: expected %d, found %d", name, x, y, want, found)
+								}
+							}
+						}
+
+						// Wrapping the other way round goes through ToYCbCr:
+						Subsampled{Base: SubImage{Base: Plasma{W: size.X, H: size.Y}, Origin: s.Origin, Pad: pad}, Ratio: ratio}.YCbCr(nil)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package testimg
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parse builds an *image.RGBA from a textual description: a legend that maps keys
+// to colours, a blank line, then a grid of keys with one row per line. For example:
+//
+//	. #00000000
+//	r #FF0000
+//	g #00FF0080
+//
+//	rrgg
+//	r..g
+//
+// Colours are non-premultiplied hex in #RRGGBB or #RRGGBBAA form. Unlike
+// rgba.FromNRGBAHex, the leading '#' is required. Keys may be any string of non-space
+// characters, but every key in a legend must have the same number of characters;
+// each row of the grid must be the width of the image multiplied by that number.
+//
+// Leading and trailing blank lines and all leading and trailing whitespace on each
+// line are ignored, so descriptions can be indented in a raw string literal.
+//
+func Parse(s string) (*image.RGBA, error) {
+	pal, rows, err := parseText(s)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, idx := range row {
+			img.Set(x, y, pal[idx])
+		}
+	}
+	return img, nil
+}
+
+// ParsePaletted is like Parse, but builds an *image.Paletted. The palette contains
+// the legend's colours in the order they are listed.
+func ParsePaletted(s string) (*image.Paletted, error) {
+	pal, rows, err := parseText(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(pal) > 256 {
+		return nil, fmt.Errorf("testimg: legend has %d colours, paletted images support 256", len(pal))
+	}
+	img := image.NewPaletted(image.Rect(0, 0, len(rows[0]), len(rows)), pal)
+	for y, row := range rows {
+		for x, idx := range row {
+			img.Pix[y*img.Stride+x] = uint8(idx)
+		}
+	}
+	return img, nil
+}
+
+// MustParse is like Parse, but panics if s is invalid.
+func MustParse(s string) *image.RGBA {
+	img, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return img
+}
+
+// MustParsePaletted is like ParsePaletted, but panics if s is invalid.
+func MustParsePaletted(s string) *image.Paletted {
+	img, err := ParsePaletted(s)
+	if err != nil {
+		panic(err)
+	}
+	return img
+}
+
+func parseText(s string) (pal color.Palette, rows [][]int, err error) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+
+	keyWidth := 0
+	lookup := map[string]int{}
+
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			break
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, nil, fmt.Errorf("testimg: legend line %d: expected key and colour, found %q", i+1, line)
+		}
+		key, hex := fields[0], fields[1]
+		if w := utf8.RuneCountInString(key); keyWidth == 0 {
+			keyWidth = w
+		} else if w != keyWidth {
+			return nil, nil, fmt.Errorf("testimg: legend line %d: key %q is not %d characters wide", i+1, key, keyWidth)
+		}
+		if _, ok := lookup[key]; ok {
+			return nil, nil, fmt.Errorf("testimg: legend line %d: duplicate key %q", i+1, key)
+		}
+		c, err := parseNRGBAHex(hex)
+		if err != nil {
+			return nil, nil, fmt.Errorf("testimg: legend line %d: %w", i+1, err)
+		}
+		lookup[key] = len(pal)
+		pal = append(pal, c)
+	}
+	if len(pal) == 0 {
+		return nil, nil, fmt.Errorf("testimg: missing legend")
+	}
+
+	for i++; i < len(lines); i++ {
+		line := []rune(strings.TrimSpace(lines[i]))
+		if len(line)%keyWidth != 0 {
+			return nil, nil, fmt.Errorf("testimg: grid line %d: length %d is not a multiple of key width %d", i+1, len(line), keyWidth)
+		}
+		row := make([]int, len(line)/keyWidth)
+		for x := range row {
+			key := string(line[x*keyWidth : (x+1)*keyWidth])
+			idx, ok := lookup[key]
+			if !ok {
+				return nil, nil, fmt.Errorf("testimg: grid line %d: unknown key %q", i+1, key)
+			}
+			row[x] = idx
+		}
+		if len(rows) > 0 && len(row) != len(rows[0]) {
+			return nil, nil, fmt.Errorf("testimg: grid line %d: width %d does not match first row width %d", i+1, len(row), len(rows[0]))
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return nil, nil, fmt.Errorf("testimg: missing grid")
+	}
+	return pal, rows, nil
+}
+
+func parseNRGBAHex(s string) (c color.NRGBA, err error) {
+	if !strings.HasPrefix(s, "#") || (len(s) != 7 && len(s) != 9) {
+		return c, fmt.Errorf("invalid hex colour %q", s)
+	}
+	var v [4]uint8
+	v[3] = 0xff
+	for i := 0; i < (len(s)-1)/2; i++ {
+		hi, lo := hexDigit(s[1+i*2]), hexDigit(s[2+i*2])
+		if hi < 0 || lo < 0 {
+			return c, fmt.Errorf("invalid hex colour %q", s)
+		}
+		v[i] = uint8(hi<<4 | lo)
+	}
+	return color.NRGBA{R: v[0], G: v[1], B: v[2], A: v[3]}, nil
+}
+
+func hexDigit(b byte) int {
+	switch {
+	case b >= '0' && b <= '9':
+		return int(b - '0')
+	case b >= 'a' && b <= 'f':
+		return int(b-'a') + 10
+	case b >= 'A' && b <= 'F':
+		return int(b-'A') + 10
+	}
+	return -1
+}
+
+// dumpKeys are used in order to name the colours in a Dump. If there are more
+// colours than keys, keys are made of several characters.
+const dumpKeys = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Dump prints the region r of img in the form accepted by Parse. If r is empty,
+// the whole image is printed. The output always ends with a newline.
+//
+// If img is an *image.Paletted, the legend lists the whole palette in order, so the
+// output can be read back with ParsePaletted to produce the same palette. Pixels
+// with an index outside the palette are printed as '?', which Parse rejects.
+// Otherwise, colours are listed in the order they are first found, and if there are
+// fully transparent pixels, they are given the key ".".
+//
+// Colours are printed non-premultiplied, so partially transparent colours in
+// premultiplied images may not survive a round trip exactly.
+//
+func Dump(img image.Image, r image.Rectangle) string {
+	if r.Empty() {
+		r = img.Bounds()
+	}
+	r = r.Intersect(img.Bounds())
+
+	var pal color.Palette
+	var idxAt func(x, y int) int
+
+	if pimg, ok := img.(*image.Paletted); ok {
+		pal = pimg.Palette
+		idxAt = func(x, y int) int { return int(pimg.ColorIndexAt(x, y)) }
+
+	} else {
+		lookup := map[color.RGBA]int{}
+		grid := make([]int, r.Dx()*r.Dy())
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				c := rgbaAt(img, x, y)
+				idx, ok := lookup[c]
+				if !ok {
+					idx = len(pal)
+					lookup[c] = idx
+					pal = append(pal, c)
+				}
+				grid[(y-r.Min.Y)*r.Dx()+(x-r.Min.X)] = idx
+			}
+		}
+		idxAt = func(x, y int) int { return grid[(y-r.Min.Y)*r.Dx()+(x-r.Min.X)] }
+	}
+
+	keys := dumpKeyNames(pal, img)
+	unknown := "?"
+	if len(keys) > 0 {
+		unknown = strings.Repeat("?", len(keys[0]))
+	}
+
+	var sb strings.Builder
+	for i, c := range pal {
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		if n.A == 0xff {
+			fmt.Fprintf(&sb, "%s #%02X%02X%02X\n", keys[i], n.R, n.G, n.B)
+		} else {
+			fmt.Fprintf(&sb, "%s #%02X%02X%02X%02X\n", keys[i], n.R, n.G, n.B, n.A)
+		}
+	}
+	sb.WriteByte('\n')
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if idx := idxAt(x, y); idx < len(keys) {
+				sb.WriteString(keys[idx])
+			} else {
+				sb.WriteString(unknown)
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func dumpKeyNames(pal color.Palette, img image.Image) []string {
+	keys := make([]string, len(pal))
+
+	// Transparent pixels get a dot when the keys are only one character wide, and
+	// the image isn't paletted (where the dot would make the order confusing):
+	_, paletted := img.(*image.Paletted)
+	transparent := -1
+	if !paletted && len(pal) <= len(dumpKeys)+1 {
+		for i, c := range pal {
+			if _, _, _, a := c.RGBA(); a == 0 {
+				transparent = i
+				keys[i] = "."
+				break
+			}
+		}
+	}
+
+	n := len(pal)
+	if transparent >= 0 {
+		n--
+	}
+	width := 1
+	for max := len(dumpKeys); max < n; max *= len(dumpKeys) {
+		width++
+	}
+
+	next := 0
+	for i := range keys {
+		if i == transparent {
+			continue
+		}
+		key := make([]byte, width)
+		v := next
+		for j := width - 1; j >= 0; j-- {
+			key[j] = dumpKeys[v%len(dumpKeys)]
+			v /= len(dumpKeys)
+		}
+		keys[i] = string(key)
+		next++
+	}
+	return keys
+}
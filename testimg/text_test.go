@@ -0,0 +1,47 @@
+package testimg
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDumpPalettedOutOfRange(t *testing.T) {
+	img := image.NewPaletted(image.Rect(0, 0, 3, 1), color.Palette{
+		color.RGBA{0xff, 0, 0, 0xff},
+		color.RGBA{0, 0xff, 0, 0xff},
+	})
+	img.Pix = []uint8{0, 1, 2}
+
+	expected := "a #FF0000\nb #00FF00\n\nab?\n"
+	if found := Dump(img, image.Rectangle{}); found != expected {
+		t.Fatalf("expected %q, found %q", expected, found)
+	}
+	if _, err := ParsePaletted(expected); err == nil {
+		t.Fatal("expected error")
+	}
+
+	img.Palette = nil
+	if found := Dump(img, image.Rectangle{}); found != "\n???\n" {
+		t.Fatalf("found %q", found)
+	}
+}
+
+func TestDumpParseRoundTrip(t *testing.T) {
+	src := `
+		. #00000000
+		r #FF0000
+		g #00FF0080
+
+		rrgg
+		r..g
+	`
+	img := MustParse(src)
+	again := MustParse(Dump(img, image.Rectangle{}))
+	AssertImage(t, img, again, 0)
+
+	pimg := MustParsePaletted(src)
+	if found := Dump(pimg, image.Rectangle{}); found != "a #00000000\nb #FF0000\nc #00FF0080\n\nbbcc\nbaac\n" {
+		t.Fatalf("found %q", found)
+	}
+}
@@ -0,0 +1,96 @@
+package testimg
+
+import (
+	"image"
+	"image/color"
+)
+
+// YCbCrSubsampleRatios lists every ratio supported by image.YCbCr.
+var YCbCrSubsampleRatios = []image.YCbCrSubsampleRatio{
+	image.YCbCrSubsampleRatio444,
+	image.YCbCrSubsampleRatio422,
+	image.YCbCrSubsampleRatio420,
+	image.YCbCrSubsampleRatio440,
+	image.YCbCrSubsampleRatio411,
+	image.YCbCrSubsampleRatio410,
+}
+
+// ToYCbCr builds an *image.YCbCr from src with the given subsample ratio. Luma is
+// taken from every pixel; chroma is the mean of the Cb and Cr of the pixels
+// covered by each chroma sample. src is composited over black, as YCbCr has no
+// alpha.
+//
+// If the colour of src is constant across each chroma sample (for example, a
+// RandBlocks whose blocks are a multiple of 4x2), converting the result back to
+// RGB will reproduce src to within the rounding error of the YCbCr conversion,
+// which is a few units per channel at most. For any other src, the result's At
+// method is the expected RGB.
+//
+// The result has the same bounds as src, unless src is at negative coordinates,
+// where image.YCbCr doesn't work. It is then moved right and down until it isn't.
+//
+func ToYCbCr(src image.Image, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	b := src.Bounds()
+	_, ib := ycbcrNonNegative(b, b)
+	img := image.NewYCbCr(ib, ratio)
+
+	type sum struct{ cb, cr, n int }
+	sums := make([]sum, len(img.Cb))
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := color.RGBAModel.Convert(src.At(b.Min.X+x, b.Min.Y+y)).(color.RGBA)
+			yy, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+			ix, iy := ib.Min.X+x, ib.Min.Y+y
+			img.Y[img.YOffset(ix, iy)] = yy
+			s := &sums[img.COffset(ix, iy)]
+			s.cb += int(cb)
+			s.cr += int(cr)
+			s.n++
+		}
+	}
+
+	for i, s := range sums {
+		if s.n > 0 {
+			img.Cb[i] = uint8((s.cb + s.n/2) / s.n)
+			img.Cr[i] = uint8((s.cr + s.n/2) / s.n)
+		}
+	}
+	return img
+}
+
+// Subsampled wraps another Recipe so that its YCbCr method produces an image with
+// the given subsample ratio, using ToYCbCr. All other methods are passed through to
+// Base unchanged.
+type Subsampled struct {
+	Base  Recipe
+	Ratio image.YCbCrSubsampleRatio
+}
+
+func (s Subsampled) YCbCr(rng RNG) *image.YCbCr {
+	return ToYCbCr(s.Base.RGBA(rng), s.Ratio)
+}
+
+func (s Subsampled) RGBA(rng RNG) *image.RGBA       { return s.Base.RGBA(rng) }
+func (s Subsampled) RGBA64(rng RNG) *image.RGBA64   { return s.Base.RGBA64(rng) }
+func (s Subsampled) NRGBA(rng RNG) *image.NRGBA     { return s.Base.NRGBA(rng) }
+func (s Subsampled) NRGBA64(rng RNG) *image.NRGBA64 { return s.Base.NRGBA64(rng) }
+func (s Subsampled) CMYK(rng RNG) *image.CMYK       { return s.Base.CMYK(rng) }
+func (s Subsampled) Paletted(rng RNG, pal color.Palette) *image.Paletted {
+	return s.Base.Paletted(rng, pal)
+}
+
+// ycbcrNonNegative moves canvas and sub right and down together until no part of
+// canvas is at a negative coordinate. image.YCbCr finds chroma samples by dividing
+// coordinates, which rounds towards zero, so subsampled images with negative
+// coordinates read and write the wrong samples, or past the end of Cb and Cr.
+func ycbcrNonNegative(canvas, sub image.Rectangle) (image.Rectangle, image.Rectangle) {
+	var shift image.Point
+	if canvas.Min.X < 0 {
+		shift.X = -canvas.Min.X
+	}
+	if canvas.Min.Y < 0 {
+		shift.Y = -canvas.Min.Y
+	}
+	return canvas.Add(shift), sub.Add(shift)
+}